@@ -23,6 +23,11 @@
 // - 🧩 Plugin system for extending syntax support
 package markit
 
+import (
+	"sort"
+	"strings"
+)
+
 // Node 表示 AST 中的一个节点
 type Node interface {
 	// Type 返回节点类型
@@ -44,6 +49,7 @@ const (
 	NodeTypeDoctype
 	NodeTypeCDATA
 	NodeTypeComment
+	NodeTypeFragment
 )
 
 // Document 表示文档根节点
@@ -63,12 +69,253 @@ type Element struct {
 	Children   []Node
 	SelfClose  bool
 	Pos        Position
+	// AttributeOrder 属性在源码中出现的顺序，渲染时在不排序的情况下按此顺序输出
+	AttributeOrder []string
+	// EmptyAttributes 显式赋值为空字符串的属性名（如 class=""），未记录的空值属性视为布尔属性
+	EmptyAttributes map[string]bool
+	// DecodedAttributes 解析时经过实体解码的属性名（源码中的 '&' 均构成合法引用），
+	// 其值中的 '&' 是解码得到的字符，CheckWellFormed 验证时不视为未转义
+	DecodedAttributes map[string]bool
+	// AttributePos 每个属性名在源码中的起始位置，仅在 TrackAttributePositions 开启时填充
+	AttributePos map[string]Position
+	// Namespaces 作用域内的命名空间前缀绑定（"" 表示默认命名空间），仅在 NamespaceAware 开启时填充
+	Namespaces map[string]string
+	// Parent 父节点（*Element、*Fragment 或 *Document），由解析器和 WithChildren 设置，独立节点为 nil
+	Parent Node
+	// CloseTagPos 结束标签 </tag> 的位置，仅在 TrackCloseTagPositions 开启且元素有结束标签时记录
+	CloseTagPos Position
 }
 
 func (e *Element) Type() NodeType     { return NodeTypeElement }
 func (e *Element) Position() Position { return e.Pos }
 func (e *Element) String() string     { return e.TagName }
 
+// 保留前缀的命名空间
+const (
+	XMLNamespace   = "http://www.w3.org/XML/1998/namespace"
+	XMLNSNamespace = "http://www.w3.org/2000/xmlns/"
+)
+
+// AttributeNamespace 返回属性名前缀解析出的命名空间 URI
+// 无前缀的属性不属于任何命名空间，无法解析的前缀返回空字符串
+func (e *Element) AttributeNamespace(name string) string {
+	if name == "xmlns" {
+		return XMLNSNamespace
+	}
+
+	i := strings.IndexByte(name, ':')
+	if i <= 0 {
+		return ""
+	}
+
+	prefix := name[:i]
+	switch prefix {
+	case "xml":
+		return XMLNamespace
+	case "xmlns":
+		return XMLNSNamespace
+	}
+
+	if uri, ok := e.Namespaces[prefix]; ok {
+		return uri
+	}
+	// 未开启命名空间解析时，退回到元素自身的声明
+	return e.Attributes["xmlns:"+prefix]
+}
+
+// DataAttributes 返回所有 data-* 属性，键为去掉 "data-" 前缀后的名称
+func (e *Element) DataAttributes() map[string]string {
+	return e.DataAttributesWithConfig(nil)
+}
+
+// DataAttributesWithConfig 按配置的大小写规则返回所有 data-* 属性
+// 大小写不敏感（如 HTMLConfig）时 DATA-User-Id 同样匹配，并以小写名称 user-id 作为键
+func (e *Element) DataAttributesWithConfig(config *ParserConfig) map[string]string {
+	data := make(map[string]string)
+	for name, value := range e.Attributes {
+		if config != nil {
+			name = config.NormalizeCase(name)
+		}
+		if key := strings.TrimPrefix(name, "data-"); key != name && key != "" {
+			data[key] = value
+		}
+	}
+	return data
+}
+
+// GetAttribute 返回属性值及其是否存在，Attributes 为 nil 时同样安全
+func (e *Element) GetAttribute(name string) (string, bool) {
+	value, ok := e.Attributes[name]
+	return value, ok
+}
+
+// FirstText 返回第一个直接文本子节点的内容，没有文本子节点时返回 ("", false)
+func (e *Element) FirstText() (string, bool) {
+	for _, child := range e.Children {
+		if text, ok := child.(*Text); ok {
+			return text.Content, true
+		}
+	}
+	return "", false
+}
+
+// Depth 沿 Parent 指针统计元素祖先的数量，顶层元素为 0
+// 片段不计入深度，也不记录父节点，位于片段中的元素只统计到片段为止
+func (e *Element) Depth() int {
+	depth := 0
+	for parent, ok := e.Parent.(*Element); ok; parent, ok = parent.Parent.(*Element) {
+		depth++
+	}
+	return depth
+}
+
+// SetAttribute 设置属性值，新属性追加到属性顺序末尾
+// 空字符串值记录到 EmptyAttributes，按显式空值（name=""）而非布尔属性渲染
+func (e *Element) SetAttribute(name, value string) {
+	if e.Attributes == nil {
+		e.Attributes = make(map[string]string)
+	}
+	if _, exists := e.Attributes[name]; !exists {
+		e.AttributeOrder = append(e.AttributeOrder, name)
+	}
+	e.Attributes[name] = value
+	if value == "" {
+		if e.EmptyAttributes == nil {
+			e.EmptyAttributes = make(map[string]bool)
+		}
+		e.EmptyAttributes[name] = true
+	} else {
+		delete(e.EmptyAttributes, name)
+	}
+	delete(e.DecodedAttributes, name)
+}
+
+// RemoveAttribute 移除属性并同步更新属性顺序
+func (e *Element) RemoveAttribute(name string) {
+	delete(e.Attributes, name)
+	delete(e.EmptyAttributes, name)
+	delete(e.DecodedAttributes, name)
+	delete(e.AttributePos, name)
+	for i, key := range e.AttributeOrder {
+		if key == name {
+			e.AttributeOrder = append(e.AttributeOrder[:i:i], e.AttributeOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// CloneNode 深拷贝节点及其子树
+func CloneNode(node Node) Node {
+	switch n := node.(type) {
+	case *Document:
+		clone := *n
+		clone.Children = cloneChildren(n.Children)
+		adoptChildren(&clone, clone.Children)
+		return &clone
+	case *Element:
+		clone := *n
+		clone.Attributes = cloneStringMap(n.Attributes)
+		if n.AttributeOrder != nil {
+			clone.AttributeOrder = append([]string(nil), n.AttributeOrder...)
+		}
+		if n.EmptyAttributes != nil {
+			clone.EmptyAttributes = make(map[string]bool, len(n.EmptyAttributes))
+			for k, v := range n.EmptyAttributes {
+				clone.EmptyAttributes[k] = v
+			}
+		}
+		if n.DecodedAttributes != nil {
+			clone.DecodedAttributes = make(map[string]bool, len(n.DecodedAttributes))
+			for k, v := range n.DecodedAttributes {
+				clone.DecodedAttributes[k] = v
+			}
+		}
+		if n.AttributePos != nil {
+			clone.AttributePos = make(map[string]Position, len(n.AttributePos))
+			for k, v := range n.AttributePos {
+				clone.AttributePos[k] = v
+			}
+		}
+		clone.Children = cloneChildren(n.Children)
+		clone.Parent = nil
+		adoptChildren(&clone, clone.Children)
+		return &clone
+	case *Fragment:
+		clone := *n
+		clone.Children = cloneChildren(n.Children)
+		adoptChildren(&clone, clone.Children)
+		return &clone
+	case *Text:
+		clone := *n
+		return &clone
+	case *ProcessingInstruction:
+		clone := *n
+		clone.PseudoAttributes = cloneStringMap(n.PseudoAttributes)
+		return &clone
+	case *Doctype:
+		clone := *n
+		clone.Entities = cloneStringMap(n.Entities)
+		clone.Elements = cloneStringMap(n.Elements)
+		return &clone
+	case *CDATA:
+		clone := *n
+		return &clone
+	case *Comment:
+		clone := *n
+		return &clone
+	default:
+		return node
+	}
+}
+
+// cloneChildren 深拷贝子节点列表
+func cloneChildren(children []Node) []Node {
+	if children == nil {
+		return nil
+	}
+	clone := make([]Node, len(children))
+	for i, child := range children {
+		clone[i] = CloneNode(child)
+	}
+	return clone
+}
+
+// adoptChildren 将子元素的父节点指向 parent
+func adoptChildren(parent Node, children []Node) {
+	for _, child := range children {
+		setParent(child, parent)
+	}
+}
+
+// setParent 设置元素节点的父节点（*Element、*Fragment 或 *Document），其他类型的节点不记录父节点
+func setParent(child Node, parent Node) {
+	if elem, ok := child.(*Element); ok {
+		elem.Parent = parent
+	}
+}
+
+// cloneStringMap 拷贝字符串映射
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+// Fragment 表示片段节点 <>...</>，仅在 AllowEmptyTags 开启时由解析器生成
+type Fragment struct {
+	Children []Node
+	Pos      Position
+}
+
+func (f *Fragment) Type() NodeType     { return NodeTypeFragment }
+func (f *Fragment) Position() Position { return f.Pos }
+func (f *Fragment) String() string     { return "Fragment" }
+
 // Text 表示文本节点
 type Text struct {
 	Content string
@@ -84,6 +331,8 @@ type ProcessingInstruction struct {
 	Target  string
 	Content string
 	Pos     Position
+	// PseudoAttributes 伪属性（如 xml-stylesheet 的 type、href），仅在 ParsePseudoAttributes 开启时解析
+	PseudoAttributes map[string]string
 }
 
 func (pi *ProcessingInstruction) Type() NodeType     { return NodeTypeProcessingInstruction }
@@ -92,14 +341,29 @@ func (pi *ProcessingInstruction) String() string     { return pi.Target }
 
 // Doctype 表示DOCTYPE声明节点
 type Doctype struct {
+	// Content 内部子集之前的声明内容，如 html 或 r SYSTEM "r.dtd"
 	Content string
-	Pos     Position
+	// InternalSubset 内部子集 [...] 的原始内容（不含方括号），渲染时原样输出
+	InternalSubset string
+	// Entities 内部子集中声明的内部通用实体（名称到替换文本）
+	Entities map[string]string
+	// Elements 内部子集中声明的元素（名称到内容模型）
+	Elements map[string]string
+	Pos      Position
 }
 
 func (dt *Doctype) Type() NodeType     { return NodeTypeDoctype }
 func (dt *Doctype) Position() Position { return dt.Pos }
 func (dt *Doctype) String() string     { return dt.Content }
 
+// declaration 返回 <!DOCTYPE 与 > 之间的完整内容，包含内部子集
+func (dt *Doctype) declaration() string {
+	if dt.InternalSubset == "" {
+		return dt.Content
+	}
+	return dt.Content + " [" + dt.InternalSubset + "]"
+}
+
 // CDATA 表示CDATA节点
 type CDATA struct {
 	Content string
@@ -120,6 +384,42 @@ func (c *Comment) Type() NodeType     { return NodeTypeComment }
 func (c *Comment) Position() Position { return c.Pos }
 func (c *Comment) String() string     { return c.Content }
 
+// Attr 表示一个属性键值对，用于构造元素
+type Attr struct {
+	Key   string
+	Value string
+}
+
+// NewElement 创建元素，属性按传入顺序记录
+func NewElement(tag string, attrs ...Attr) *Element {
+	elem := &Element{
+		TagName:    tag,
+		Attributes: make(map[string]string, len(attrs)),
+		Children:   []Node{},
+	}
+	for _, attr := range attrs {
+		elem.SetAttribute(attr.Key, attr.Value)
+	}
+	return elem
+}
+
+// WithChildren 追加子节点并返回元素本身，便于链式构造
+func (e *Element) WithChildren(children ...Node) *Element {
+	e.Children = append(e.Children, children...)
+	adoptChildren(e, children)
+	return e
+}
+
+// NewText 创建文本节点
+func NewText(content string) *Text {
+	return &Text{Content: content}
+}
+
+// NewComment 创建注释节点
+func NewComment(content string) *Comment {
+	return &Comment{Content: content}
+}
+
 // AttributeProcessor 属性处理器接口
 type AttributeProcessor interface {
 	// ProcessAttribute 处理属性，返回处理后的键值对
@@ -129,7 +429,22 @@ type AttributeProcessor interface {
 }
 
 // DefaultAttributeProcessor 默认属性处理器
-type DefaultAttributeProcessor struct{}
+// 零值使用 HTML5 标准布尔属性集合，NewAttributeProcessor 或 Add/RemoveBooleanAttribute 可定制该集合
+type DefaultAttributeProcessor struct {
+	booleans map[string]bool // nil 表示使用 defaultBooleanAttributes
+}
+
+// NewAttributeProcessor 创建使用指定布尔属性集合的属性处理器，booleanAttrs 为 nil 时使用默认集合
+func NewAttributeProcessor(booleanAttrs []string) *DefaultAttributeProcessor {
+	p := &DefaultAttributeProcessor{}
+	if booleanAttrs != nil {
+		p.booleans = make(map[string]bool, len(booleanAttrs))
+		for _, name := range booleanAttrs {
+			p.booleans[name] = true
+		}
+	}
+	return p
+}
 
 func (p *DefaultAttributeProcessor) ProcessAttribute(key, value string) (string, interface{}, error) {
 	// 如果值为空，认为是布尔属性
@@ -140,24 +455,71 @@ func (p *DefaultAttributeProcessor) ProcessAttribute(key, value string) (string,
 }
 
 func (p *DefaultAttributeProcessor) IsBooleanAttribute(key string) bool {
-	// HTML5 标准布尔属性列表
-	booleanAttrs := map[string]bool{
-		"checked":   true,
-		"disabled":  true,
-		"selected":  true,
-		"readonly":  true,
-		"required":  true,
-		"autofocus": true,
-		"autoplay":  true,
-		"controls":  true,
-		"defer":     true,
-		"hidden":    true,
-		"loop":      true,
-		"multiple":  true,
-		"muted":     true,
-		"open":      true,
-		"reversed":  true,
-		"scoped":    true,
-	}
-	return booleanAttrs[key]
+	return p.booleanSet()[key]
+}
+
+// BooleanAttributeNames 返回按名称排序的布尔属性列表
+func (p *DefaultAttributeProcessor) BooleanAttributeNames() []string {
+	return sortedKeys(p.booleanSet())
+}
+
+// AddBooleanAttribute 将属性注册为布尔属性
+func (p *DefaultAttributeProcessor) AddBooleanAttribute(name string) {
+	p.ownBooleans()[name] = true
+}
+
+// RemoveBooleanAttribute 取消属性的布尔属性注册
+func (p *DefaultAttributeProcessor) RemoveBooleanAttribute(name string) {
+	delete(p.ownBooleans(), name)
+}
+
+// booleanSet 返回当前生效的布尔属性集合
+func (p *DefaultAttributeProcessor) booleanSet() map[string]bool {
+	if p.booleans == nil {
+		return defaultBooleanAttributes
+	}
+	return p.booleans
+}
+
+// ownBooleans 返回处理器独有的布尔属性集合，首次修改时从默认集合复制，避免修改共享的默认集合
+func (p *DefaultAttributeProcessor) ownBooleans() map[string]bool {
+	if p.booleans == nil {
+		p.booleans = make(map[string]bool, len(defaultBooleanAttributes)+1)
+		for name := range defaultBooleanAttributes {
+			p.booleans[name] = true
+		}
+	}
+	return p.booleans
+}
+
+// defaultBooleanAttributes HTML5 标准布尔属性列表
+var defaultBooleanAttributes = map[string]bool{
+	"checked":   true,
+	"disabled":  true,
+	"selected":  true,
+	"readonly":  true,
+	"required":  true,
+	"autofocus": true,
+	"autoplay":  true,
+	"controls":  true,
+	"defer":     true,
+	"hidden":    true,
+	"loop":      true,
+	"multiple":  true,
+	"muted":     true,
+	"open":      true,
+	"reversed":  true,
+	"scoped":    true,
+}
+
+// sortedKeys 返回值为 true 的键并按名称排序
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key, ok := range set {
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
 }
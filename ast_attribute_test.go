@@ -267,3 +267,228 @@ func TestAttributeProcessorEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// TestAttributeOrderPreservation 测试属性顺序在修改和克隆后保持一致
+func TestAttributeOrderPreservation(t *testing.T) {
+	render := func(t *testing.T, elem *Element) string {
+		t.Helper()
+		renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true, SortAttributes: false})
+		output, err := renderer.RenderElement(elem)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		return output
+	}
+
+	doc, err := NewParser(`<item z="1" a="2" m="3"/>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	elem := doc.Children[0].(*Element)
+
+	t.Run("parsed order", func(t *testing.T) {
+		if got := render(t, elem); got != `<item z="1" a="2" m="3" />` {
+			t.Errorf("unexpected output %q", got)
+		}
+	})
+
+	t.Run("set appends new and keeps existing position", func(t *testing.T) {
+		elem.SetAttribute("b", "4")
+		elem.SetAttribute("z", "9")
+		if got := render(t, elem); got != `<item z="9" a="2" m="3" b="4" />` {
+			t.Errorf("unexpected output %q", got)
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		elem.RemoveAttribute("a")
+		elem.RemoveAttribute("missing")
+		if got := render(t, elem); got != `<item z="9" m="3" b="4" />` {
+			t.Errorf("unexpected output %q", got)
+		}
+		if len(elem.AttributeOrder) != 3 {
+			t.Errorf("expected 3 ordered attributes, got %v", elem.AttributeOrder)
+		}
+	})
+
+	t.Run("clone is independent", func(t *testing.T) {
+		clone := CloneNode(elem).(*Element)
+		clone.SetAttribute("c", "5")
+		clone.RemoveAttribute("z")
+
+		if got := render(t, clone); got != `<item m="3" b="4" c="5" />` {
+			t.Errorf("unexpected clone output %q", got)
+		}
+		if got := render(t, elem); got != `<item z="9" m="3" b="4" />` {
+			t.Errorf("original modified by clone: %q", got)
+		}
+	})
+
+	t.Run("set on element without attributes", func(t *testing.T) {
+		e := &Element{TagName: "x"}
+		e.SetAttribute("id", "1")
+		if got := render(t, e); got != `<x id="1"></x>` {
+			t.Errorf("unexpected output %q", got)
+		}
+	})
+}
+
+// TestCloneNode 测试节点深拷贝
+func TestCloneNode(t *testing.T) {
+	doc, err := NewParser(`<root a="1"><p>text</p><!-- c --></root>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	clone := CloneNode(doc).(*Document)
+	if !NodesEqual(doc, clone) {
+		t.Fatalf("clone differs: %v", Diff(doc, clone))
+	}
+
+	root := clone.Children[0].(*Element)
+	root.Attributes["a"] = "2"
+	root.Children[0].(*Element).Children[0].(*Text).Content = "changed"
+	if doc.Children[0].(*Element).Attributes["a"] != "1" {
+		t.Error("original attributes modified by clone")
+	}
+	if doc.Children[0].(*Element).Children[0].(*Element).Children[0].(*Text).Content != "text" {
+		t.Error("original text modified by clone")
+	}
+}
+
+// TestAttributeNamespace 测试带命名空间前缀的属性
+func TestAttributeNamespace(t *testing.T) {
+	input := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">
+		<g><use xlink:href="#a" href="#b" xml:lang="en" other:attr="x"/></g>
+	</svg>`
+
+	config := DefaultConfig()
+	config.NamespaceAware = true
+	doc, err := NewParserWithConfig(input, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	svg := doc.Children[0].(*Element)
+	g := svg.Children[0].(*Element)
+	use := g.Children[0].(*Element)
+
+	if use.Attributes["xlink:href"] != "#a" || use.Attributes["href"] != "#b" {
+		t.Fatalf("expected both href attributes, got %v", use.Attributes)
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"xlink:href", "http://www.w3.org/1999/xlink"},
+		{"href", ""},
+		{"xml:lang", XMLNamespace},
+		{"other:attr", ""},
+		{"xmlns", XMLNSNamespace},
+	}
+	for _, tt := range tests {
+		if got := use.AttributeNamespace(tt.name); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+
+	if use.Namespaces[""] != "http://www.w3.org/2000/svg" {
+		t.Errorf("expected inherited default namespace, got %v", use.Namespaces)
+	}
+
+	t.Run("inner declaration shadows outer", func(t *testing.T) {
+		doc, err := NewParserWithConfig(`<a xmlns:p="urn:outer"><b xmlns:p="urn:inner" p:x="1"/><c p:x="2"></c></a>`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		a := doc.Children[0].(*Element)
+		if got := a.Children[0].(*Element).AttributeNamespace("p:x"); got != "urn:inner" {
+			t.Errorf("expected urn:inner, got %q", got)
+		}
+		if got := a.Children[1].(*Element).AttributeNamespace("p:x"); got != "urn:outer" {
+			t.Errorf("expected urn:outer, got %q", got)
+		}
+	})
+
+	t.Run("without namespace mode uses own declarations", func(t *testing.T) {
+		doc, _ := NewParser(`<use xmlns:xlink="urn:xlink" xlink:href="#a"/>`).Parse()
+		use := doc.Children[0].(*Element)
+		if use.Namespaces != nil {
+			t.Errorf("expected no namespace scope, got %v", use.Namespaces)
+		}
+		if got := use.AttributeNamespace("xlink:href"); got != "urn:xlink" {
+			t.Errorf("expected urn:xlink, got %q", got)
+		}
+	})
+}
+
+// TestDataAttributes 测试 data-* 属性分组
+func TestDataAttributes(t *testing.T) {
+	input := `<div id="card" data-user-id="5" data-role="admin" class="x" data="plain" data-="empty" DATA-Theme="dark"></div>`
+
+	doc, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	div := doc.Children[0].(*Element)
+
+	data := div.DataAttributes()
+	expected := map[string]string{"user-id": "5", "role": "admin"}
+	if len(data) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, data)
+	}
+	for key, value := range expected {
+		if data[key] != value {
+			t.Errorf("expected %s=%q, got %q", key, value, data[key])
+		}
+	}
+
+	t.Run("case-insensitive under HTML config", func(t *testing.T) {
+		config := HTMLConfig()
+		doc, err := NewParserWithConfig(input, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		data := doc.Children[0].(*Element).DataAttributesWithConfig(config)
+		if len(data) != 3 || data["theme"] != "dark" || data["user-id"] != "5" {
+			t.Errorf("unexpected data attributes %v", data)
+		}
+	})
+
+	t.Run("no data attributes", func(t *testing.T) {
+		if data := (&Element{TagName: "p"}).DataAttributes(); len(data) != 0 {
+			t.Errorf("expected empty map, got %v", data)
+		}
+	})
+}
+
+// TestNilAttributeMap 测试无属性元素的属性映射为 nil
+func TestNilAttributeMap(t *testing.T) {
+	doc, err := NewParser(`<root><item/><item id="1"/></root>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	root := doc.Children[0].(*Element)
+	bare := root.Children[0].(*Element)
+	withID := root.Children[1].(*Element)
+
+	if root.Attributes != nil || bare.Attributes != nil {
+		t.Errorf("expected nil attribute maps, got %v and %v", root.Attributes, bare.Attributes)
+	}
+	if value, ok := bare.GetAttribute("id"); ok || value != "" {
+		t.Errorf("expected missing attribute, got %q", value)
+	}
+	if value, ok := withID.GetAttribute("id"); !ok || value != "1" {
+		t.Errorf("expected id=1, got %q", value)
+	}
+
+	bare.SetAttribute("class", "c")
+	output, err := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if output != `<root><item class="c" /><item id="1" /></root>` {
+		t.Errorf("unexpected output %s", output)
+	}
+}
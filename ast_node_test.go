@@ -285,3 +285,94 @@ func TestCDATANode(t *testing.T) {
 		t.Errorf("Expected CDATA content, got '%s'", cdata.String())
 	}
 }
+
+// TestElementCreationHelpers 测试节点构造辅助函数
+func TestElementCreationHelpers(t *testing.T) {
+	doc := &Document{Children: []Node{
+		NewElement("ul", Attr{"class", "menu"}, Attr{"id", "nav"}).WithChildren(
+			NewComment(" items "),
+			NewElement("li").WithChildren(NewText("Home")),
+			NewElement("li", Attr{Key: "class", Value: "active"}).WithChildren(NewText("About & Contact")),
+		),
+	}}
+
+	renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true, EscapeText: true})
+	output, err := renderer.RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+
+	expected := `<ul class="menu" id="nav"><!-- items --><li>Home</li><li class="active">About &amp; Contact</li></ul>`
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+
+	parsed, err := NewParserWithConfig(output, XMLConfig()).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	// 解析结果与构造的文档结构一致（注释内容被修剪）
+	doc.Children[0].(*Element).Children[0].(*Comment).Content = "items"
+	if !NodesEqual(doc, parsed) {
+		t.Errorf("built document differs from parsed output: %v", Diff(doc, parsed))
+	}
+
+	t.Run("empty element", func(t *testing.T) {
+		elem := NewElement("br")
+		if elem.Attributes == nil || elem.Children == nil {
+			t.Error("expected initialized attributes and children")
+		}
+		if len(elem.AttributeOrder) != 0 {
+			t.Errorf("expected no attribute order, got %v", elem.AttributeOrder)
+		}
+	})
+}
+
+// TestElementFirstText 测试获取第一个直接文本子节点
+func TestElementFirstText(t *testing.T) {
+	doc, err := NewParser(`<head><title>Hi</title><p><b>bold</b>tail</p><empty></empty></head>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	head := doc.Children[0].(*Element)
+
+	tests := []struct {
+		name    string
+		elem    *Element
+		want    string
+		present bool
+	}{
+		{"leaf text", head.Children[0].(*Element), "Hi", true},
+		{"element child first", head.Children[1].(*Element), "tail", true},
+		{"empty element", head.Children[2].(*Element), "", false},
+		{"element children only", head, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.elem.FirstText()
+			if got != tt.want || ok != tt.present {
+				t.Errorf("expected (%q, %t), got (%q, %t)", tt.want, tt.present, got, ok)
+			}
+		})
+	}
+}
+
+func TestElementDepth(t *testing.T) {
+	doc, err := NewParser(`<html><body><div><p>text</p></div><span></span></body></html>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	expected := map[string]int{"html": 0, "body": 1, "div": 2, "p": 3, "span": 2}
+	_ = WalkElements(doc, func(e *Element) error {
+		if got := e.Depth(); got != expected[e.TagName] {
+			t.Errorf("<%s>: expected depth %d, got %d", e.TagName, expected[e.TagName], got)
+		}
+		return nil
+	})
+
+	if depth := NewElement("detached").Depth(); depth != 0 {
+		t.Errorf("expected detached element depth 0, got %d", depth)
+	}
+}
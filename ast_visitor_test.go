@@ -1,6 +1,7 @@
 package markit
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -436,3 +437,130 @@ func TestWalkSingleTextNode(t *testing.T) {
 		}
 	}
 }
+
+// TestWalkDepth 测试限制深度的遍历
+func TestWalkDepth(t *testing.T) {
+	// Document -> root -> (a -> (x, "t1"), b, "t2")
+	doc, err := NewParser(`<root><a><x/>t1</a><b/>t2</root>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	tests := []struct {
+		maxDepth int
+		expected int
+	}{
+		{0, 1}, // Document
+		{1, 2}, // + root
+		{2, 5}, // + a, b, t2
+		{3, 7}, // + x, t1
+		{10, 7},
+	}
+
+	for _, tt := range tests {
+		visitor := &CountingVisitor{}
+		if err := WalkDepth(doc, tt.maxDepth, visitor); err != nil {
+			t.Fatalf("walk error: %v", err)
+		}
+		if visitor.count != tt.expected {
+			t.Errorf("maxDepth %d: expected %d nodes, got %d", tt.maxDepth, tt.expected, visitor.count)
+		}
+	}
+
+	t.Run("starting from element", func(t *testing.T) {
+		root := doc.Children[0]
+		visitor := &CountingVisitor{}
+		if err := WalkDepth(root, 1, visitor); err != nil {
+			t.Fatalf("walk error: %v", err)
+		}
+		if visitor.count != 4 {
+			t.Errorf("expected root and 3 direct children, got %d", visitor.count)
+		}
+	})
+
+	t.Run("fragments are transparent", func(t *testing.T) {
+		config := DefaultConfig()
+		config.AllowEmptyTags = true
+		doc, err := NewParserWithConfig(`<root><><><a><b/></a></></></root>`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		visitor := &CountingVisitor{}
+		if err := WalkDepth(doc, 2, visitor); err != nil {
+			t.Fatalf("walk error: %v", err)
+		}
+		// Document、root 和 a，片段下更深的 b 不被访问
+		if visitor.count != 3 {
+			t.Errorf("expected 3 nodes within depth 2, got %d", visitor.count)
+		}
+	})
+
+	t.Run("full depth matches Walk", func(t *testing.T) {
+		full := &CountingVisitor{}
+		limited := &CountingVisitor{}
+		_ = Walk(doc, full)
+		_ = WalkDepth(doc, 100, limited)
+		if full.count != limited.count {
+			t.Errorf("expected %d nodes, got %d", full.count, limited.count)
+		}
+	})
+}
+
+// customNode 用于测试的自定义节点类型
+type customNode struct{}
+
+func (customNode) Type() NodeType     { return NodeTypeText }
+func (customNode) Position() Position { return Position{} }
+func (customNode) String() string     { return "custom" }
+
+// TestWalkLeafRoots 测试以叶子节点作为起点时只调用对应的访问方法
+func TestWalkLeafRoots(t *testing.T) {
+	leaves := []Node{
+		NewText("text"),
+		NewComment("comment"),
+		&ProcessingInstruction{Target: "xml", Content: `version="1.0"`},
+		&Doctype{Content: "html"},
+		&CDATA{Content: "data"},
+	}
+
+	for _, leaf := range leaves {
+		t.Run(nodeTypeName(leaf.Type()), func(t *testing.T) {
+			visitor := &WalkTestVisitor{}
+			if err := Walk(leaf, visitor); err != nil {
+				t.Fatalf("walk error: %v", err)
+			}
+			if len(visitor.visitedNodes) != 1 || visitor.visitedNodes[0] != leaf {
+				t.Errorf("expected only the root to be visited, got %v", visitor.visitedNodes)
+			}
+		})
+	}
+}
+
+// TestWalkStrictUnknownNode 测试未知节点类型的处理
+func TestWalkStrictUnknownNode(t *testing.T) {
+	visitor := &WalkTestVisitor{}
+	if err := Walk(customNode{}, visitor); err != nil {
+		t.Errorf("Walk should ignore unknown node types, got %v", err)
+	}
+	if len(visitor.visitedNodes) != 0 {
+		t.Errorf("expected no visits, got %d", len(visitor.visitedNodes))
+	}
+
+	if err := WalkStrict(customNode{}, visitor); !errors.Is(err, ErrUnknownNodeType) {
+		t.Errorf("expected ErrUnknownNodeType for root, got %v", err)
+	}
+
+	doc := &Document{Children: []Node{NewElement("a"), customNode{}}}
+	visitor = &WalkTestVisitor{}
+	err := WalkStrict(doc, visitor)
+	if !errors.Is(err, ErrUnknownNodeType) || !strings.Contains(err.Error(), "customNode") {
+		t.Errorf("expected ErrUnknownNodeType naming the type, got %v", err)
+	}
+	if len(visitor.visitedNodes) != 2 {
+		t.Errorf("expected nodes before the unknown one to be visited, got %d", len(visitor.visitedNodes))
+	}
+
+	if err := WalkStrict(NewText("x"), &WalkTestVisitor{}); err != nil {
+		t.Errorf("known leaf should not error, got %v", err)
+	}
+}
@@ -339,3 +339,67 @@ func TestAttributeProcessorEdgeCasesAdvanced(t *testing.T) {
 		}
 	})
 }
+
+// TestCustomBooleanAttributes 测试注册自定义布尔属性
+func TestCustomBooleanAttributes(t *testing.T) {
+	processor := NewAttributeProcessor([]string{"my-flag"})
+	if !processor.IsBooleanAttribute("my-flag") || processor.IsBooleanAttribute("checked") {
+		t.Error("custom processor should only know my-flag")
+	}
+
+	t.Run("parsing coercion", func(t *testing.T) {
+		config := DefaultConfig()
+		config.AttributeProcessor = processor
+		doc, err := NewParserWithConfig(`<r><x my-flag/><x my-flag=""/><x my-flag="my-flag"/><x other=""/></r>`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		children := doc.Children[0].(*Element).Children
+		for i, child := range children[:2] {
+			elem := child.(*Element)
+			if value, ok := elem.Attributes["my-flag"]; !ok || value != "" || elem.EmptyAttributes["my-flag"] {
+				t.Errorf("element %d: expected bare my-flag, got %q (explicit empty: %v)", i, value, elem.EmptyAttributes["my-flag"])
+			}
+		}
+		if value := children[2].(*Element).Attributes["my-flag"]; value != "my-flag" {
+			t.Errorf("expected non-empty boolean value kept, got %q", value)
+		}
+		if !children[3].(*Element).EmptyAttributes["other"] {
+			t.Error("non-boolean explicit empty value should not be coerced")
+		}
+
+		output, err := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		if output != `<r><x my-flag /><x my-flag /><x my-flag="my-flag" /><x other="" /></r>` {
+			t.Errorf("unexpected output %s", output)
+		}
+
+		// 渲染器同样使用配置中的处理器识别布尔属性
+		output, _ = NewRendererWithConfig(config, &RenderOptions{CompactMode: true, EmptyValueStyle: HTML5BooleanValue}).RenderToString(doc)
+		if output != `<r><x my-flag /><x my-flag /><x my-flag /><x other="" /></r>` {
+			t.Errorf("unexpected HTML5 boolean output %s", output)
+		}
+
+		// 默认处理器不认识 my-flag，显式空值保持不变
+		doc, _ = NewParser(`<x my-flag=""/>`).Parse()
+		if !doc.Children[0].(*Element).EmptyAttributes["my-flag"] {
+			t.Error("default processor should keep my-flag as an explicit empty value")
+		}
+	})
+
+	t.Run("add and remove", func(t *testing.T) {
+		p := &DefaultAttributeProcessor{}
+		p.AddBooleanAttribute("my-flag")
+		p.RemoveBooleanAttribute("hidden")
+		if !p.IsBooleanAttribute("my-flag") || !p.IsBooleanAttribute("checked") || p.IsBooleanAttribute("hidden") {
+			t.Error("unexpected boolean set after add/remove")
+		}
+
+		defaults := NewAttributeProcessor(nil)
+		if defaults.IsBooleanAttribute("my-flag") || !defaults.IsBooleanAttribute("hidden") {
+			t.Error("modifying one processor must not change the default set")
+		}
+	})
+}
@@ -44,6 +44,36 @@ func BenchmarkLexerComplex(b *testing.B) {
 	}
 }
 
+// BenchmarkLexerWhitespaceHeavy 基准测试：大量缩进的词法分析
+func BenchmarkLexerWhitespaceHeavy(b *testing.B) {
+	input := whitespaceHeavyInput(200)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lexer := NewLexer(input)
+		for {
+			token := lexer.NextToken()
+			if token.Type == TokenEOF {
+				break
+			}
+		}
+	}
+}
+
+// whitespaceHeavyInput 生成带有深度缩进的输入
+func whitespaceHeavyInput(items int) string {
+	var builder strings.Builder
+	builder.WriteString("<root>\n")
+	for i := 0; i < items; i++ {
+		indent := strings.Repeat("    ", i%16+1)
+		builder.WriteString(indent + "<item   id=\"x\"   class=\"y\"   >\n")
+		builder.WriteString(indent + "        text content\t\t\n")
+		builder.WriteString(indent + "</item>\n\n")
+	}
+	builder.WriteString("</root>\n")
+	return builder.String()
+}
+
 // BenchmarkParserSimple 基准测试：简单解析
 func BenchmarkParserSimple(b *testing.B) {
 	input := `<element attr="value">text</element>`
@@ -151,6 +181,29 @@ func BenchmarkMemoryAllocation(b *testing.B) {
 	}
 }
 
+// BenchmarkAttributeLight 基准测试：属性稀少的文档
+func BenchmarkAttributeLight(b *testing.B) {
+	var builder strings.Builder
+	builder.WriteString("<list>")
+	for i := 0; i < 100; i++ {
+		if i%10 == 0 {
+			builder.WriteString(`<item id="x"><b>text</b></item>`)
+		} else {
+			builder.WriteString("<item><b>text</b></item>")
+		}
+	}
+	builder.WriteString("</list>")
+	input := builder.String()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewParser(input).Parse(); err != nil {
+			b.Fatalf("parsing failed: %v", err)
+		}
+	}
+}
+
 // BenchmarkNestedElements 基准测试：嵌套元素
 func BenchmarkNestedElements(b *testing.B) {
 	// 生成深度嵌套的文档
@@ -185,6 +238,21 @@ func BenchmarkNestedElements(b *testing.B) {
 	}
 }
 
+// BenchmarkDeeplyNestedElements 基准测试：超深嵌套元素
+func BenchmarkDeeplyNestedElements(b *testing.B) {
+	depth := 5000
+	input := strings.Repeat("<level>", depth) + "content" + strings.Repeat("</level>", depth)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser := NewParser(input)
+		_, err := parser.Parse()
+		if err != nil {
+			b.Fatalf("parsing failed: %v", err)
+		}
+	}
+}
+
 // BenchmarkSelfClosingElements 基准测试：自闭合元素
 func BenchmarkSelfClosingElements(b *testing.B) {
 	var builder strings.Builder
@@ -297,3 +365,38 @@ func BenchmarkTokenTypes(b *testing.B) {
 		})
 	}
 }
+
+// BenchmarkRenderLarge 基准测试：大文档渲染，对比未预分配的缓冲区与预分配的 RenderToString
+func BenchmarkRenderLarge(b *testing.B) {
+	var builder strings.Builder
+	builder.WriteString("<root>")
+	for i := 0; i < 1000; i++ {
+		builder.WriteString(`<item id="x" class="test">Content</item>`)
+	}
+	builder.WriteString("</root>")
+
+	doc, err := NewParser(builder.String()).Parse()
+	if err != nil {
+		b.Fatalf("parsing failed: %v", err)
+	}
+	renderer := NewRenderer()
+
+	b.Run("Unsized", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var sb strings.Builder
+			if err := renderer.RenderToWriter(doc, &sb); err != nil {
+				b.Fatalf("render failed: %v", err)
+			}
+		}
+	})
+
+	b.Run("Presized", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := renderer.RenderToString(doc); err != nil {
+				b.Fatalf("render failed: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package markit
+
+import "fmt"
+
+// DocumentBuilder 以链式调用构造文档
+// Element 打开新元素并成为后续调用的当前元素，End 关闭当前元素
+type DocumentBuilder struct {
+	doc   *Document
+	stack []*Element
+	err   error
+}
+
+// NewDocumentBuilder 创建文档构造器
+func NewDocumentBuilder() *DocumentBuilder {
+	return &DocumentBuilder{doc: &Document{Children: []Node{}}}
+}
+
+// Element 在当前位置添加元素并进入该元素
+func (b *DocumentBuilder) Element(tag string) *DocumentBuilder {
+	elem := NewElement(tag)
+	b.append(elem)
+	b.stack = append(b.stack, elem)
+	return b
+}
+
+// Attr 为当前元素设置属性
+func (b *DocumentBuilder) Attr(name, value string) *DocumentBuilder {
+	if len(b.stack) == 0 {
+		b.fail(fmt.Errorf("attribute %q set outside of an element", name))
+		return b
+	}
+	b.stack[len(b.stack)-1].SetAttribute(name, value)
+	return b
+}
+
+// Text 在当前位置添加文本节点
+func (b *DocumentBuilder) Text(content string) *DocumentBuilder {
+	b.append(NewText(content))
+	return b
+}
+
+// Comment 在当前位置添加注释节点
+func (b *DocumentBuilder) Comment(content string) *DocumentBuilder {
+	b.append(NewComment(content))
+	return b
+}
+
+// End 关闭当前元素，回到其父元素
+func (b *DocumentBuilder) End() *DocumentBuilder {
+	if len(b.stack) == 0 {
+		b.fail(fmt.Errorf("End called with no open element"))
+		return b
+	}
+	b.stack = b.stack[:len(b.stack)-1]
+	return b
+}
+
+// Build 返回构造完成的文档，存在未关闭的元素或不匹配的 End 调用时返回错误
+func (b *DocumentBuilder) Build() (*Document, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.stack) > 0 {
+		return nil, fmt.Errorf("unclosed element <%s>", b.stack[len(b.stack)-1].TagName)
+	}
+	return b.doc, nil
+}
+
+// append 将节点添加到当前元素或文档
+func (b *DocumentBuilder) append(node Node) {
+	if len(b.stack) == 0 {
+		b.doc.Children = append(b.doc.Children, node)
+		setParent(node, b.doc)
+		return
+	}
+	b.stack[len(b.stack)-1].WithChildren(node)
+}
+
+// fail 记录第一个错误
+func (b *DocumentBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
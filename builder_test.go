@@ -0,0 +1,59 @@
+package markit
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDocumentBuilder 测试链式构造文档
+func TestDocumentBuilder(t *testing.T) {
+	doc, err := NewDocumentBuilder().
+		Comment(" generated ").
+		Element("div").Attr("class", "x").Attr("id", "main").
+		Text("hello").
+		Comment("note").
+		Element("ul").
+		Element("li").Text("one").End().
+		Element("li").Text("two").End().
+		End().
+		End().
+		Build()
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	output, err := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	expected := `<!-- generated --><div class="x" id="main">hello<!--note--><ul><li>one</li><li>two</li></ul></div>`
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+
+	li := doc.FindAll(func(e *Element) bool { return e.TagName == "li" })
+	if len(li) != 2 || ElementPath(li[1]) != "div/ul/li[2]" {
+		t.Errorf("expected parent links to be set, got %d li elements", len(li))
+	}
+
+	t.Run("unbalanced End", func(t *testing.T) {
+		_, err := NewDocumentBuilder().Element("a").End().End().Build()
+		if err == nil || !strings.Contains(err.Error(), "no open element") {
+			t.Errorf("expected unbalanced End error, got %v", err)
+		}
+	})
+
+	t.Run("unclosed element", func(t *testing.T) {
+		_, err := NewDocumentBuilder().Element("a").Element("b").End().Build()
+		if err == nil || err.Error() != "unclosed element <a>" {
+			t.Errorf("expected unclosed element error, got %v", err)
+		}
+	})
+
+	t.Run("attribute outside element", func(t *testing.T) {
+		_, err := NewDocumentBuilder().Attr("x", "1").Build()
+		if err == nil {
+			t.Error("expected error for attribute outside element")
+		}
+	})
+}
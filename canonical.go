@@ -0,0 +1,153 @@
+package markit
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// CanonicalOptions 规范化选项
+type CanonicalOptions struct {
+	// RemoveComments 是否移除注释节点
+	RemoveComments bool
+}
+
+// Canonicalize 返回文档的规范化副本（C14N-lite），不修改原文档
+// 属性按名称排序，默认命名空间声明传播到作用域内的每个元素，
+// 空元素展开为 <e></e>，非 xml:space="preserve" 作用域内的文本空白被规范化
+func Canonicalize(doc *Document) *Document {
+	return CanonicalizeWithOptions(doc, &CanonicalOptions{})
+}
+
+// CanonicalizeWithOptions 使用指定选项返回文档的规范化副本
+func CanonicalizeWithOptions(doc *Document, opts *CanonicalOptions) *Document {
+	if doc == nil {
+		return nil
+	}
+	if opts == nil {
+		opts = &CanonicalOptions{}
+	}
+
+	result := CloneNode(doc).(*Document)
+	result.Children = canonicalizeChildren(result.Children, opts, "", false)
+	return result
+}
+
+// canonicalizeChildren 规范化子节点列表
+// defaultNS 为父作用域的默认命名空间，preserve 表示父作用域要求保留空白
+func canonicalizeChildren(children []Node, opts *CanonicalOptions, defaultNS string, preserve bool) []Node {
+	result := make([]Node, 0, len(children))
+	for _, child := range children {
+		switch n := child.(type) {
+		case *Comment:
+			if opts.RemoveComments {
+				continue
+			}
+		case *Text:
+			// 合并相邻文本节点（如移除注释后留下的文本片段）
+			if last := len(result) - 1; last >= 0 {
+				if prev, ok := result[last].(*Text); ok {
+					prev.Content += n.Content
+					continue
+				}
+			}
+		case *Element:
+			canonicalizeElement(n, opts, defaultNS, preserve)
+		case *Fragment:
+			n.Children = canonicalizeChildren(n.Children, opts, defaultNS, preserve)
+		}
+		result = append(result, child)
+	}
+
+	if preserve {
+		return result
+	}
+
+	normalized := result[:0]
+	for _, child := range result {
+		if text, ok := child.(*Text); ok {
+			text.Content = normalizeSpace(text.Content)
+			if text.Content == "" {
+				continue
+			}
+		}
+		normalized = append(normalized, child)
+	}
+	return normalized
+}
+
+// canonicalizeElement 就地规范化已拷贝的元素及其子树
+func canonicalizeElement(elem *Element, opts *CanonicalOptions, defaultNS string, preserve bool) {
+	if ns, ok := elem.Attributes["xmlns"]; ok {
+		defaultNS = ns
+	} else if defaultNS != "" {
+		if elem.Attributes == nil {
+			elem.Attributes = make(map[string]string)
+		}
+		elem.Attributes["xmlns"] = defaultNS
+	}
+
+	switch elem.Attributes["xml:space"] {
+	case "preserve":
+		preserve = true
+	case "default":
+		preserve = false
+	}
+
+	keys := make([]string, 0, len(elem.Attributes))
+	for key := range elem.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	elem.AttributeOrder = keys
+
+	elem.SelfClose = false
+	elem.Children = canonicalizeChildren(elem.Children, opts, defaultNS, preserve)
+}
+
+// normalizeSpace 去除首尾空白并将连续空白折叠为单个空格
+func normalizeSpace(s string) string {
+	return strings.Join(strings.FieldsFunc(s, unicode.IsSpace), " ")
+}
+
+// SortChildren 使用 less 稳定排序元素的子元素，用于比较顺序无关的集合
+// 仅子元素参与排序并重新填入原先由元素占据的位置，文本、注释等节点保持原位
+func SortChildren(e *Element, less func(a, b Node) bool) {
+	var slots []int
+	var elems []Node
+	for i, child := range e.Children {
+		if _, ok := child.(*Element); ok {
+			slots = append(slots, i)
+			elems = append(elems, child)
+		}
+	}
+
+	sort.SliceStable(elems, func(i, j int) bool { return less(elems[i], elems[j]) })
+	for i, slot := range slots {
+		e.Children[slot] = elems[i]
+	}
+}
+
+// SortChildrenByTag 按标签名稳定排序元素的子元素
+func SortChildrenByTag(e *Element) {
+	SortChildren(e, func(a, b Node) bool {
+		return a.(*Element).TagName < b.(*Element).TagName
+	})
+}
+
+// NormalizeBooleanAttributes 将 processor 识别为布尔属性的属性值统一改写为空字符串（裸属性形式），
+// 使 disabled="true"、disabled="disabled" 与 disabled 归一；processor 为 nil 时使用 DefaultAttributeProcessor
+func NormalizeBooleanAttributes(doc *Document, processor AttributeProcessor) {
+	if processor == nil {
+		processor = &DefaultAttributeProcessor{}
+	}
+	_ = WalkElements(doc, func(e *Element) error {
+		for name := range e.Attributes {
+			if processor.IsBooleanAttribute(name) {
+				e.Attributes[name] = ""
+				delete(e.EmptyAttributes, name)
+			}
+		}
+		return nil
+	})
+}
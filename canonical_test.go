@@ -0,0 +1,161 @@
+package markit
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCanonicalize 测试文档规范化
+func TestCanonicalize(t *testing.T) {
+	parse := func(t *testing.T, input string) *Document {
+		t.Helper()
+		doc, err := NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		return doc
+	}
+
+	t.Run("logically equivalent documents", func(t *testing.T) {
+		a := parse(t, `<root xmlns="urn:x" b="2" a="1"><item/><p>hello   world</p></root>`)
+		b := parse(t, "<root a=\"1\" b=\"2\" xmlns=\"urn:x\">\n  <item xmlns=\"urn:x\"></item>\n  <p>\n    hello world\n  </p>\n</root>")
+		if NodesEqual(a, b) {
+			t.Fatal("expected raw documents to differ")
+		}
+		ca, cb := Canonicalize(a), Canonicalize(b)
+		if !NodesEqual(ca, cb) {
+			t.Errorf("expected canonical trees to be equal, diff: %v", Diff(ca, cb))
+		}
+	})
+
+	t.Run("normalizes copy only", func(t *testing.T) {
+		doc := parse(t, `<root z="1" a="2"><br/></root>`)
+		canonical := Canonicalize(doc)
+
+		root := canonical.Children[0].(*Element)
+		if strings.Join(root.AttributeOrder, ",") != "a,z" {
+			t.Errorf("expected sorted attribute order, got %v", root.AttributeOrder)
+		}
+		if root.Children[0].(*Element).SelfClose {
+			t.Error("expected empty element to be expanded")
+		}
+		if !doc.Children[0].(*Element).Children[0].(*Element).SelfClose {
+			t.Error("original document should not be modified")
+		}
+
+		output, err := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderToString(canonical)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		if output != `<root a="2" z="1"><br></br></root>` {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+
+	t.Run("default namespace propagation", func(t *testing.T) {
+		canonical := Canonicalize(parse(t, `<a xmlns="urn:a"><b><c xmlns="urn:c"><d/></c></b></a>`))
+		b := canonical.Children[0].(*Element).Children[0].(*Element)
+		c := b.Children[0].(*Element)
+		d := c.Children[0].(*Element)
+		if b.Attributes["xmlns"] != "urn:a" || c.Attributes["xmlns"] != "urn:c" || d.Attributes["xmlns"] != "urn:c" {
+			t.Errorf("unexpected namespaces: %q %q %q", b.Attributes["xmlns"], c.Attributes["xmlns"], d.Attributes["xmlns"])
+		}
+	})
+
+	t.Run("preserved whitespace", func(t *testing.T) {
+		config := DefaultConfig()
+		config.TrimWhitespace = false
+		doc, err := NewParserWithConfig(`<r><pre xml:space="preserve">  a  b </pre><p>  a  b </p></r>`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		r := Canonicalize(doc).Children[0].(*Element)
+		pre := r.Children[0].(*Element).Children[0].(*Text)
+		p := r.Children[1].(*Element).Children[0].(*Text)
+		if pre.Content != "  a  b " {
+			t.Errorf("expected preserved text, got %q", pre.Content)
+		}
+		if p.Content != "a b" {
+			t.Errorf("expected normalized text, got %q", p.Content)
+		}
+	})
+
+	t.Run("remove comments", func(t *testing.T) {
+		doc := parse(t, `<p>a<!-- note -->b</p>`)
+		if len(Canonicalize(doc).Children[0].(*Element).Children) != 3 {
+			t.Error("expected comments to be kept by default")
+		}
+		p := CanonicalizeWithOptions(doc, &CanonicalOptions{RemoveComments: true}).Children[0].(*Element)
+		if len(p.Children) != 1 || p.Children[0].(*Text).Content != "ab" {
+			t.Errorf("expected merged text after removing comments, got %v", p.Children)
+		}
+	})
+
+	t.Run("nil document", func(t *testing.T) {
+		if Canonicalize(nil) != nil {
+			t.Error("expected nil for nil document")
+		}
+	})
+}
+
+// TestSortChildren 测试子元素排序
+func TestSortChildren(t *testing.T) {
+	doc, err := NewParser(`<set><c id="1"/><a id="1"/>text<b/><a id="2"/><!--note--><c id="2"/></set>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	set := doc.Children[0].(*Element)
+	SortChildrenByTag(set)
+
+	var order []string
+	for _, child := range set.Children {
+		switch n := child.(type) {
+		case *Element:
+			order = append(order, n.TagName+n.Attributes["id"])
+		case *Text:
+			order = append(order, "#text")
+		case *Comment:
+			order = append(order, "#comment")
+		}
+	}
+
+	want := "a1,a2,#text,b,c1,#comment,c2"
+	if got := strings.Join(order, ","); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	SortChildren(set, func(a, b Node) bool {
+		return a.(*Element).TagName > b.(*Element).TagName
+	})
+	if first := set.Children[0].(*Element); first.TagName != "c" || first.Attributes["id"] != "1" {
+		t.Errorf("expected stable descending order starting with c1, got %s%s", first.TagName, first.Attributes["id"])
+	}
+}
+
+// TestNormalizeBooleanAttributes 测试布尔属性归一化
+func TestNormalizeBooleanAttributes(t *testing.T) {
+	doc, err := NewParser(`<form><input disabled="true"/><input disabled="disabled" value="v"/><input disabled/><input disabled=""/></form>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	NormalizeBooleanAttributes(doc, nil)
+
+	form := doc.Children[0].(*Element)
+	for i, child := range form.Children {
+		input := child.(*Element)
+		if value, ok := input.Attributes["disabled"]; !ok || value != "" || input.EmptyAttributes["disabled"] {
+			t.Errorf("input %d: expected bare disabled, got %q", i, value)
+		}
+	}
+	if value := form.Children[1].(*Element).Attributes["value"]; value != "v" {
+		t.Errorf("non-boolean attribute changed to %q", value)
+	}
+
+	output, err := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if strings.Count(output, `<input disabled />`) != 3 || strings.Contains(output, `disabled=`) {
+		t.Errorf("expected bare disabled attributes, got %s", output)
+	}
+}
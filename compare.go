@@ -0,0 +1,259 @@
+package markit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DiffEntry 描述两棵 AST 之间的一处差异
+type DiffEntry struct {
+	// Path 差异所在节点的路径，如 /root[0]/p[1]/#text[0]
+	Path string
+	// Message 差异描述
+	Message string
+}
+
+// String 返回差异的字符串表示
+func (d DiffEntry) String() string {
+	return fmt.Sprintf("%s: %s", d.Path, d.Message)
+}
+
+// EqualOptions 结构比较时可忽略的差异
+type EqualOptions struct {
+	// IgnoreComments 忽略注释节点
+	IgnoreComments bool
+	// IgnoreWhitespace 忽略仅包含空白的文本节点
+	IgnoreWhitespace bool
+	// IgnoreAttributeOrder 忽略属性顺序，否则按 AttributeOrder 记录的输出顺序比较
+	IgnoreAttributeOrder bool
+	// LooseBooleanAttributes 将布尔属性 x、x="" 与 x="x" 视为相同，只对 AttributeProcessor 识别的布尔属性生效
+	LooseBooleanAttributes bool
+	// AttributeProcessor 判断布尔属性使用的处理器（nil 表示使用 DefaultAttributeProcessor）
+	AttributeProcessor AttributeProcessor
+}
+
+// NodesEqual 深度比较两个节点的结构是否相同（忽略位置信息与属性顺序）
+func NodesEqual(a, b Node) bool {
+	return len(Diff(a, b)) == 0
+}
+
+// NodesEqualIgnoring 按选项深度比较两个节点的结构是否相同（始终忽略位置信息）
+func NodesEqualIgnoring(a, b Node, opts EqualOptions) bool {
+	var entries []DiffEntry
+	diffNode(a, b, "/", &opts, &entries)
+	return len(entries) == 0
+}
+
+// Diff 返回两个节点之间所有结构差异（忽略位置信息），按文档顺序排列
+func Diff(a, b Node) []DiffEntry {
+	var entries []DiffEntry
+	diffNode(a, b, "/", &EqualOptions{IgnoreAttributeOrder: true}, &entries)
+	return entries
+}
+
+// diffNode 比较两个节点并将差异追加到 entries
+func diffNode(a, b Node, path string, opts *EqualOptions, entries *[]DiffEntry) {
+	add := func(format string, args ...interface{}) {
+		*entries = append(*entries, DiffEntry{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if a == nil || b == nil {
+		if a != b {
+			add("node presence differs: %v vs %v", a != nil, b != nil)
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		add("node type differs: %s vs %s", nodeTypeName(a.Type()), nodeTypeName(b.Type()))
+		return
+	}
+
+	switch na := a.(type) {
+	case *Document:
+		nb := b.(*Document)
+		diffChildren(na.Children, nb.Children, path, opts, entries)
+	case *Element:
+		nb := b.(*Element)
+		if na.TagName != nb.TagName {
+			add("tag name differs: %q vs %q", na.TagName, nb.TagName)
+		}
+		if na.SelfClose != nb.SelfClose {
+			add("self-close differs: %v vs %v", na.SelfClose, nb.SelfClose)
+		}
+		attrsA, attrsB := na.Attributes, nb.Attributes
+		if opts.LooseBooleanAttributes {
+			processor := opts.AttributeProcessor
+			if processor == nil {
+				processor = &DefaultAttributeProcessor{}
+			}
+			attrsA, attrsB = looseBooleanAttributes(attrsA, processor), looseBooleanAttributes(attrsB, processor)
+		}
+		diffAttributes(attrsA, attrsB, "attribute", add)
+		if !opts.IgnoreAttributeOrder {
+			orderA, orderB := attributeKeys(na, false), attributeKeys(nb, false)
+			if strings.Join(orderA, " ") != strings.Join(orderB, " ") {
+				add("attribute order differs: %v vs %v", orderA, orderB)
+			}
+		}
+		diffChildren(na.Children, nb.Children, path, opts, entries)
+	case *Fragment:
+		diffChildren(na.Children, b.(*Fragment).Children, path, opts, entries)
+	case *Text:
+		if nb := b.(*Text); na.Content != nb.Content {
+			add("text differs: %q vs %q", na.Content, nb.Content)
+		}
+	case *Comment:
+		if nb := b.(*Comment); na.Content != nb.Content {
+			add("comment differs: %q vs %q", na.Content, nb.Content)
+		}
+	case *CDATA:
+		if nb := b.(*CDATA); na.Content != nb.Content {
+			add("CDATA differs: %q vs %q", na.Content, nb.Content)
+		}
+	case *Doctype:
+		if nb := b.(*Doctype); na.declaration() != nb.declaration() {
+			add("doctype differs: %q vs %q", na.declaration(), nb.declaration())
+		}
+	case *ProcessingInstruction:
+		nb := b.(*ProcessingInstruction)
+		if na.Target != nb.Target {
+			add("processing instruction target differs: %q vs %q", na.Target, nb.Target)
+		}
+		if na.Content != nb.Content {
+			add("processing instruction content differs: %q vs %q", na.Content, nb.Content)
+		}
+		diffAttributes(na.PseudoAttributes, nb.PseudoAttributes, "pseudo attribute", add)
+	default:
+		if a != b {
+			add("unknown node type %T", a)
+		}
+	}
+}
+
+// diffAttributes 按属性名顺序比较两组属性
+func diffAttributes(a, b map[string]string, kind string, add func(string, ...interface{})) {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		va, okA := a[key]
+		vb, okB := b[key]
+		switch {
+		case !okB:
+			add("%s %q missing in second node", kind, key)
+		case !okA:
+			add("%s %q missing in first node", kind, key)
+		case va != vb:
+			add("%s %q differs: %q vs %q", kind, key, va, vb)
+		}
+	}
+}
+
+// looseBooleanAttributes 将值等于属性名的布尔属性统一为空值
+func looseBooleanAttributes(attrs map[string]string, processor AttributeProcessor) map[string]string {
+	normalized := make(map[string]string, len(attrs))
+	for key, value := range attrs {
+		if strings.EqualFold(value, key) && processor.IsBooleanAttribute(key) {
+			value = ""
+		}
+		normalized[key] = value
+	}
+	return normalized
+}
+
+// filterChildren 移除比较时需要忽略的子节点
+func filterChildren(children []Node, opts *EqualOptions) []Node {
+	if !opts.IgnoreComments && !opts.IgnoreWhitespace {
+		return children
+	}
+
+	filtered := make([]Node, 0, len(children))
+	for _, child := range children {
+		switch n := child.(type) {
+		case *Comment:
+			if opts.IgnoreComments {
+				continue
+			}
+		case *Text:
+			if opts.IgnoreWhitespace && strings.TrimSpace(n.Content) == "" {
+				continue
+			}
+		}
+		filtered = append(filtered, child)
+	}
+	return filtered
+}
+
+// diffChildren 逐个比较子节点
+func diffChildren(a, b []Node, path string, opts *EqualOptions, entries *[]DiffEntry) {
+	a, b = filterChildren(a, opts), filterChildren(b, opts)
+	if len(a) != len(b) {
+		*entries = append(*entries, DiffEntry{
+			Path:    path,
+			Message: fmt.Sprintf("child count differs: %d vs %d", len(a), len(b)),
+		})
+	}
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		diffNode(a[i], b[i], childPath(path, a[i], i), opts, entries)
+	}
+}
+
+// childPath 计算子节点的路径
+func childPath(parent string, node Node, index int) string {
+	if parent != "/" {
+		parent += "/"
+	}
+
+	name := "#node"
+	switch n := node.(type) {
+	case *Element:
+		name = n.TagName
+	case *Fragment:
+		name = "#fragment"
+	case *Text:
+		name = "#text"
+	case *Comment:
+		name = "#comment"
+	case *CDATA:
+		name = "#cdata"
+	case *Doctype:
+		name = "#doctype"
+	case *ProcessingInstruction:
+		name = "#pi"
+	}
+	return fmt.Sprintf("%s%s[%d]", parent, name, index)
+}
+
+// nodeTypeName 返回节点类型的可读名称
+func nodeTypeName(t NodeType) string {
+	switch t {
+	case NodeTypeDocument:
+		return "document"
+	case NodeTypeElement:
+		return "element"
+	case NodeTypeText:
+		return "text"
+	case NodeTypeProcessingInstruction:
+		return "processing instruction"
+	case NodeTypeDoctype:
+		return "doctype"
+	case NodeTypeCDATA:
+		return "CDATA"
+	case NodeTypeComment:
+		return "comment"
+	case NodeTypeFragment:
+		return "fragment"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(t))
+	}
+}
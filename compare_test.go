@@ -0,0 +1,180 @@
+package markit
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNodesEqual 测试 AST 结构相等性比较
+func TestNodesEqual(t *testing.T) {
+	parse := func(t *testing.T, input string) *Document {
+		t.Helper()
+		doc, err := NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		return doc
+	}
+
+	t.Run("equal trees ignoring positions", func(t *testing.T) {
+		a := parse(t, `<root><p class="x">hello</p><!-- note --><br/></root>`)
+		b := parse(t, "<root>\n  <p class=\"x\">hello</p>\n  <!-- note -->\n  <br/>\n</root>")
+		if !NodesEqual(a, b) {
+			t.Errorf("expected equal trees, diff: %v", Diff(a, b))
+		}
+	})
+
+	t.Run("attribute order insensitive", func(t *testing.T) {
+		a := parse(t, `<item a="1" b="2" c="3"/>`)
+		b := parse(t, `<item c="3" a="1" b="2"/>`)
+		if !NodesEqual(a, b) {
+			t.Errorf("expected equal trees, diff: %v", Diff(a, b))
+		}
+	})
+
+	t.Run("different trees", func(t *testing.T) {
+		cases := [][2]string{
+			{`<a/>`, `<b/>`},
+			{`<a x="1"/>`, `<a x="2"/>`},
+			{`<a x="1"/>`, `<a/>`},
+			{`<a><b/></a>`, `<a><b/><b/></a>`},
+			{`<a>text</a>`, `<a><!--text--></a>`},
+		}
+		for _, c := range cases {
+			if NodesEqual(parse(t, c[0]), parse(t, c[1])) {
+				t.Errorf("expected %q and %q to differ", c[0], c[1])
+			}
+		}
+	})
+
+	t.Run("nil nodes", func(t *testing.T) {
+		if !NodesEqual(nil, nil) {
+			t.Error("nil nodes should be equal")
+		}
+		if NodesEqual(&Text{Content: "x"}, nil) {
+			t.Error("nil and non-nil nodes should differ")
+		}
+	})
+}
+
+// TestDiff 测试 AST 差异定位
+func TestDiff(t *testing.T) {
+	a, _ := NewParser(`<root><div><p>one</p><p>two</p></div></root>`).Parse()
+	b, _ := NewParser(`<root><div><p>one</p><p>three</p></div></root>`).Parse()
+
+	entries := Diff(a, b)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 diff entry, got %d: %v", len(entries), entries)
+	}
+	if entries[0].Path != "/root[0]/div[0]/p[1]/#text[0]" {
+		t.Errorf("unexpected path %q", entries[0].Path)
+	}
+	if !strings.Contains(entries[0].Message, `"two" vs "three"`) {
+		t.Errorf("unexpected message %q", entries[0].Message)
+	}
+
+	t.Run("attribute and child count differences", func(t *testing.T) {
+		a := &Element{TagName: "x", Attributes: map[string]string{"a": "1", "b": "2"}}
+		b := &Element{TagName: "x", Attributes: map[string]string{"b": "3", "c": "4"}, Children: []Node{&Text{Content: "t"}}}
+
+		var messages []string
+		for _, e := range Diff(a, b) {
+			messages = append(messages, e.String())
+		}
+		expected := []string{
+			`/: attribute "a" missing in second node`,
+			`/: attribute "b" differs: "2" vs "3"`,
+			`/: attribute "c" missing in first node`,
+			`/: child count differs: 0 vs 1`,
+		}
+		if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("unexpected diff:\n%s", strings.Join(messages, "\n"))
+		}
+	})
+}
+
+// TestNodesEqualIgnoring 测试按选项忽略注释、空白和属性差异的比较
+func TestNodesEqualIgnoring(t *testing.T) {
+	config := DefaultConfig()
+	config.TrimWhitespace = false
+	parse := func(t *testing.T, input string) *Document {
+		t.Helper()
+		doc, err := NewParserWithConfig(input, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		return doc
+	}
+
+	t.Run("comments and whitespace", func(t *testing.T) {
+		a := parse(t, `<root><p>hello</p><br/></root>`)
+		b := parse(t, "<root>\n  <!-- generated -->\n  <p>hello</p>\n  <br/>\n</root>")
+
+		if NodesEqualIgnoring(a, b, EqualOptions{}) {
+			t.Error("expected documents to differ without options")
+		}
+		if NodesEqualIgnoring(a, b, EqualOptions{IgnoreComments: true}) {
+			t.Error("expected whitespace differences to remain")
+		}
+		if NodesEqualIgnoring(a, b, EqualOptions{IgnoreWhitespace: true}) {
+			t.Error("expected comment differences to remain")
+		}
+		if !NodesEqualIgnoring(a, b, EqualOptions{IgnoreComments: true, IgnoreWhitespace: true}) {
+			t.Error("expected documents to be equal ignoring comments and whitespace")
+		}
+	})
+
+	t.Run("attribute order", func(t *testing.T) {
+		a := parse(t, `<item a="1" b="2"/>`)
+		b := parse(t, `<item b="2" a="1"/>`)
+		if NodesEqualIgnoring(a, b, EqualOptions{}) {
+			t.Error("expected attribute order to matter")
+		}
+		if !NodesEqualIgnoring(a, b, EqualOptions{IgnoreAttributeOrder: true}) {
+			t.Error("expected equal ignoring attribute order")
+		}
+	})
+
+	t.Run("loose boolean attributes", func(t *testing.T) {
+		a := parse(t, `<input disabled checked=""/>`)
+		b := parse(t, `<input disabled="disabled" checked="checked"/>`)
+		if NodesEqualIgnoring(a, b, EqualOptions{}) {
+			t.Error("expected boolean attribute forms to differ")
+		}
+		if !NodesEqualIgnoring(a, b, EqualOptions{LooseBooleanAttributes: true}) {
+			t.Error("expected boolean attribute forms to be equal")
+		}
+		c := parse(t, `<input disabled="no" checked=""/>`)
+		if NodesEqualIgnoring(a, c, EqualOptions{LooseBooleanAttributes: true}) {
+			t.Error("expected non-boolean value to differ")
+		}
+
+		// 非布尔属性的空值与等于属性名的值仍然不同
+		for _, pair := range [][2]string{
+			{`<input id=""/>`, `<input id="id"/>`},
+			{`<input value="value"/>`, `<input value=""/>`},
+		} {
+			if NodesEqualIgnoring(parse(t, pair[0]), parse(t, pair[1]), EqualOptions{LooseBooleanAttributes: true}) {
+				t.Errorf("expected %s and %s to differ", pair[0], pair[1])
+			}
+		}
+
+		// 自定义处理器声明的布尔属性
+		d, e := parse(t, `<x my-flag/>`), parse(t, `<x my-flag="my-flag"/>`)
+		if NodesEqualIgnoring(d, e, EqualOptions{LooseBooleanAttributes: true}) {
+			t.Error("expected unknown attribute to be compared strictly")
+		}
+		opts := EqualOptions{LooseBooleanAttributes: true, AttributeProcessor: NewAttributeProcessor([]string{"my-flag"})}
+		if !NodesEqualIgnoring(d, e, opts) {
+			t.Error("expected configured boolean attribute to compare loosely")
+		}
+	})
+
+	t.Run("significant text still compared", func(t *testing.T) {
+		a := parse(t, `<p> hello </p>`)
+		b := parse(t, `<p>hello</p>`)
+		if NodesEqualIgnoring(a, b, EqualOptions{IgnoreWhitespace: true}) {
+			t.Error("expected non-whitespace-only text to be compared exactly")
+		}
+	})
+}
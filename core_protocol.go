@@ -1,5 +1,10 @@
 package markit
 
+import (
+	"fmt"
+	"strings"
+)
+
 // CoreProtocol MarkIt 核心协议定义
 // 这些是 MarkIt 的内置协议，不能被覆盖或移除
 type CoreProtocol struct {
@@ -9,6 +14,8 @@ type CoreProtocol struct {
 	SelfClose   string
 	TokenType   TokenType
 	Description string
+	// Priority 开始序列长度相同时的匹配优先级，数值大者优先
+	Priority int
 }
 
 // GetCoreProtocols 返回 MarkIt 的核心协议
@@ -34,10 +41,37 @@ func GetCoreProtocols() []CoreProtocol {
 	}
 }
 
+// NewCoreProtocolMatcherWithTagSyntax 创建自定义标准标签定界符的核心协议匹配器
+// 例如使用 "[" 和 "]" 可以解析 [b]bold[/b] 这类 BBCode 风格的标签
+func NewCoreProtocolMatcherWithTagSyntax(openSeq, closeSeq string) *CoreProtocolMatcher {
+	matcher := NewCoreProtocolMatcher()
+	for i := range matcher.protocols {
+		if matcher.protocols[i].Name == "markit-standard-tag" {
+			matcher.protocols[i].OpenSeq = openSeq
+			matcher.protocols[i].CloseSeq = closeSeq
+			matcher.protocols[i].Description = fmt.Sprintf("MarkIt standard tags %stag%s", openSeq, closeSeq)
+		}
+	}
+
+	matcher.index()
+	return matcher
+}
+
+// StandardTag 返回标准标签协议
+func (cpm *CoreProtocolMatcher) StandardTag() *CoreProtocol {
+	for i := range cpm.protocols {
+		if cpm.protocols[i].Name == "markit-standard-tag" {
+			return &cpm.protocols[i]
+		}
+	}
+	return nil
+}
+
 // CoreProtocolMatcher MarkIt 核心协议匹配器
 type CoreProtocolMatcher struct {
-	protocols []CoreProtocol
-	maxLen    int
+	protocols  []CoreProtocol
+	maxLen     int
+	startBytes [256]bool // 各协议开始序列的首字节，用于快速排除
 }
 
 // NewCoreProtocolMatcher 创建核心协议匹配器
@@ -48,30 +82,54 @@ func NewCoreProtocolMatcher() *CoreProtocolMatcher {
 		maxLen:    0,
 	}
 
-	// 计算最大长度
-	for _, protocol := range protocols {
-		if len(protocol.OpenSeq) > matcher.maxLen {
-			matcher.maxLen = len(protocol.OpenSeq)
+	matcher.index()
+	return matcher
+}
+
+// Clone 返回匹配器的副本，协议列表独立于原匹配器
+func (cpm *CoreProtocolMatcher) Clone() *CoreProtocolMatcher {
+	clone := *cpm
+	clone.protocols = append([]CoreProtocol(nil), cpm.protocols...)
+	return &clone
+}
+
+// index 计算最大长度和首字节表
+func (cpm *CoreProtocolMatcher) index() {
+	cpm.maxLen = 0
+	cpm.startBytes = [256]bool{}
+	for _, protocol := range cpm.protocols {
+		if len(protocol.OpenSeq) > cpm.maxLen {
+			cpm.maxLen = len(protocol.OpenSeq)
+		}
+		if protocol.OpenSeq != "" {
+			cpm.startBytes[protocol.OpenSeq[0]] = true
 		}
 	}
+}
 
-	return matcher
+// CanStart 检查字节是否可能是某个协议开始序列的首字节
+func (cpm *CoreProtocolMatcher) CanStart(b byte) bool {
+	return cpm.startBytes[b]
 }
 
 // MatchProtocol 匹配核心协议
+// 多个协议的开始序列都能匹配时（如 "<!" 与 "<!--"），开始序列最长的优先；
+// 长度相同时 Priority 大者优先，仍相同时先加入匹配器的协议优先
 func (cpm *CoreProtocolMatcher) MatchProtocol(input string, pos int) *CoreProtocol {
-	// 按开始序列长度从长到短匹配，确保最长匹配优先
-	for length := cpm.maxLen; length >= 1; length-- {
-		if pos+length > len(input) {
+	if pos >= len(input) {
+		return nil
+	}
+
+	var best *CoreProtocol
+	for i := range cpm.protocols {
+		protocol := &cpm.protocols[i]
+		if protocol.OpenSeq == "" || !strings.HasPrefix(input[pos:], protocol.OpenSeq) {
 			continue
 		}
-
-		candidate := input[pos : pos+length]
-		for i := range cpm.protocols {
-			if cpm.protocols[i].OpenSeq == candidate {
-				return &cpm.protocols[i]
-			}
+		if best == nil || len(protocol.OpenSeq) > len(best.OpenSeq) ||
+			(len(protocol.OpenSeq) == len(best.OpenSeq) && protocol.Priority > best.Priority) {
+			best = protocol
 		}
 	}
-	return nil
+	return best
 }
@@ -67,6 +67,38 @@ func TestCoreProtocolMatcher(t *testing.T) {
 	}
 }
 
+// TestCoreProtocolMatcherLongestFirst 测试开始序列重叠时的匹配顺序
+func TestCoreProtocolMatcherLongestFirst(t *testing.T) {
+	matcher := NewCoreProtocolMatcher()
+	// 较短的 "<!" 协议在 "<!--" 之后加入，仍不能抢占注释
+	matcher.protocols = append(matcher.protocols, CoreProtocol{Name: "bang", OpenSeq: "<!", CloseSeq: ">"})
+	matcher.index()
+
+	if protocol := matcher.MatchProtocol("<!-- x -->", 0); protocol == nil || protocol.Name != "markit-comment" {
+		t.Errorf("expected comment protocol, got %v", protocol)
+	}
+	if protocol := matcher.MatchProtocol("<!foo>", 0); protocol == nil || protocol.Name != "bang" {
+		t.Errorf("expected bang protocol, got %v", protocol)
+	}
+	if protocol := matcher.MatchProtocol("<a>", 0); protocol == nil || protocol.Name != "markit-standard-tag" {
+		t.Errorf("expected standard tag, got %v", protocol)
+	}
+
+	// 长度相同时 Priority 大者优先，否则先加入的优先
+	matcher.protocols = append(matcher.protocols, CoreProtocol{Name: "bang-2", OpenSeq: "<!", CloseSeq: ">"})
+	if protocol := matcher.MatchProtocol("<!foo>", 0); protocol.Name != "bang" {
+		t.Errorf("expected earlier protocol on tie, got %s", protocol.Name)
+	}
+	matcher.protocols[len(matcher.protocols)-1].Priority = 1
+	if protocol := matcher.MatchProtocol("<!foo>", 0); protocol.Name != "bang-2" {
+		t.Errorf("expected higher priority protocol, got %s", protocol.Name)
+	}
+
+	if protocol := matcher.MatchProtocol("<!", 5); protocol != nil {
+		t.Errorf("expected nil past end of input, got %v", protocol)
+	}
+}
+
 func TestCoreParserBasic(t *testing.T) {
 	input := "<root>Hello World</root>"
 	parser := NewParser(input)
@@ -0,0 +1,118 @@
+package markit
+
+import "strings"
+
+// doctypeEnd 返回从 start 开始的 DOCTYPE 声明结束符 '>' 的字节偏移，未找到时返回 -1
+// 引号内的内容和内部子集中的声明、注释不会结束 DOCTYPE
+func doctypeEnd(input string, start int) int {
+	inSubset := false
+	for i := start; i < len(input); i++ {
+		switch c := input[i]; {
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(input[i+1:], c)
+			if end < 0 {
+				return -1
+			}
+			i += end + 1
+		case inSubset && strings.HasPrefix(input[i:], "<!--"):
+			end := strings.Index(input[i+4:], "-->")
+			if end < 0 {
+				return -1
+			}
+			i += end + 4 + len("-->") - 1
+		case c == '[':
+			inSubset = true
+		case c == ']':
+			inSubset = false
+		case c == '>' && !inSubset:
+			return i
+		}
+	}
+	return -1
+}
+
+// splitDoctype 将 DOCTYPE 内容拆分为内部子集之前的部分和内部子集（不含方括号）
+func splitDoctype(content string) (declaration, subset string) {
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; c {
+		case '"', '\'':
+			end := strings.IndexByte(content[i+1:], c)
+			if end < 0 {
+				return content, ""
+			}
+			i += end + 1
+		case '[':
+			end := strings.LastIndexByte(content, ']')
+			if end < i {
+				return content, ""
+			}
+			return strings.TrimSpace(content[:i]), content[i+1 : end]
+		}
+	}
+	return content, ""
+}
+
+// parseInternalSubset 从内部子集中解析内部通用实体（名称到替换文本）和元素声明（名称到内容模型）
+// 参数实体、外部实体和其他声明会被跳过，没有对应声明时返回 nil
+func parseInternalSubset(subset string) (entities, elements map[string]string) {
+	for i := 0; i < len(subset); {
+		rest := subset[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return entities, elements
+			}
+			i += 4 + end + len("-->")
+			continue
+		case strings.HasPrefix(rest, "<"):
+			end := doctypeEnd(rest, 1)
+			if end < 0 {
+				return entities, elements
+			}
+			decl := rest[1:end]
+			if body, ok := strings.CutPrefix(decl, "!ENTITY"); ok {
+				if name, value, ok := parseEntityDeclaration(body); ok {
+					if entities == nil {
+						entities = make(map[string]string)
+					}
+					entities[name] = value
+				}
+			} else if body, ok := strings.CutPrefix(decl, "!ELEMENT"); ok {
+				if fields := strings.Fields(body); len(fields) > 0 {
+					if elements == nil {
+						elements = make(map[string]string)
+					}
+					elements[fields[0]] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(body), fields[0]))
+				}
+			}
+			i += end + 1
+			continue
+		}
+		i++
+	}
+	return entities, elements
+}
+
+// parseEntityDeclaration 解析 <!ENTITY name "value"> 中 ENTITY 之后的部分，仅接受内部通用实体
+func parseEntityDeclaration(body string) (name, value string, ok bool) {
+	body = strings.TrimSpace(body)
+	end := strings.IndexFunc(body, isXMLWhitespace)
+	if end <= 0 || body[0] == '%' {
+		return "", "", false
+	}
+	name, literal := body[:end], strings.TrimSpace(body[end:])
+	if literal == "" || (literal[0] != '"' && literal[0] != '\'') {
+		return "", "", false
+	}
+	close := strings.IndexByte(literal[1:], literal[0])
+	if close < 0 {
+		return "", "", false
+	}
+	return name, literal[1 : close+1], true
+}
+
+// isXMLWhitespace 检查是否为 XML 空白字符
+func isXMLWhitespace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
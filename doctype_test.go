@@ -0,0 +1,73 @@
+package markit
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDoctypeInternalSubset 测试 DOCTYPE 内部子集的解析与往返渲染
+func TestDoctypeInternalSubset(t *testing.T) {
+	input := `<!DOCTYPE r [ <!ENTITY x "y"> ]>`
+	doc, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	doctype, ok := doc.Children[0].(*Doctype)
+	if !ok {
+		t.Fatalf("expected Doctype, got %T", doc.Children[0])
+	}
+	if doctype.Content != "r" || doctype.InternalSubset != ` <!ENTITY x "y"> ` {
+		t.Errorf("unexpected doctype %q / %q", doctype.Content, doctype.InternalSubset)
+	}
+	if doctype.Entities["x"] != "y" {
+		t.Errorf("expected entity x=y, got %v", doctype.Entities)
+	}
+
+	output, err := NewRendererWithOptions(&RenderOptions{CompactMode: true, IncludeDeclaration: true}).RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if output != input {
+		t.Errorf("expected %s, got %s", input, output)
+	}
+}
+
+// TestDoctypeDeclarations 测试内部子集中的实体和元素声明
+func TestDoctypeDeclarations(t *testing.T) {
+	input := `<!doctype note SYSTEM "note.dtd" [
+  <!-- it's a <comment> -->
+  <!ELEMENT note (to,body)>
+  <!ENTITY writer 'Ada > Bob'>
+  <!ENTITY % param "skip">
+  <!ENTITY logo SYSTEM "logo.gif">
+]><note/>`
+	doc, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(doc.Children) != 2 {
+		t.Fatalf("expected doctype and root element, got %d nodes", len(doc.Children))
+	}
+
+	doctype := doc.Children[0].(*Doctype)
+	if doctype.Content != `note SYSTEM "note.dtd"` {
+		t.Errorf("unexpected content %q", doctype.Content)
+	}
+	if len(doctype.Entities) != 1 || doctype.Entities["writer"] != "Ada > Bob" {
+		t.Errorf("expected only the internal general entity, got %v", doctype.Entities)
+	}
+	if doctype.Elements["note"] != "(to,body)" {
+		t.Errorf("expected element declaration, got %v", doctype.Elements)
+	}
+
+	plain, _ := NewParser(`<!DOCTYPE html><html></html>`).Parse()
+	if dt := plain.Children[0].(*Doctype); dt.Content != "html" || dt.InternalSubset != "" || dt.Entities != nil {
+		t.Errorf("unexpected plain doctype %+v", dt)
+	}
+
+	_, err = NewParser(`<!DOCTYPE r [ <!ENTITY x "y">`).Parse()
+	if err == nil || !strings.Contains(err.Error(), "unterminated DOCTYPE") {
+		t.Errorf("expected unterminated DOCTYPE error, got %v", err)
+	}
+}
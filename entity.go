@@ -0,0 +1,97 @@
+package markit
+
+import (
+	"strconv"
+	"strings"
+)
+
+// xmlEntities XML 预定义实体
+var xmlEntities = map[string]string{
+	"amp":  "&",
+	"lt":   "<",
+	"gt":   ">",
+	"quot": "\"",
+	"apos": "'",
+}
+
+// decodeEntities 解码 XML 预定义实体和数字字符引用，无法识别的引用原样保留
+func decodeEntities(s string) string {
+	return decodeEntitiesWith(s, nil)
+}
+
+// decodeEntitiesWith 与 decodeEntities 相同，无法识别的命名实体交给 unknown 解析，
+// unknown 返回 false 时原样保留
+func decodeEntitiesWith(s string, unknown func(name string) (string, bool)) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '&' {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		end := strings.IndexByte(s[i+1:], ';')
+		if end <= 0 {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		name := s[i+1 : i+1+end]
+		value, ok := decodeEntity(name)
+		if !ok && unknown != nil && isEntityName(name) {
+			value, ok = unknown(name)
+		}
+		if ok {
+			sb.WriteString(value)
+			i += end + 1
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// decodeEntity 解码单个实体引用（不含 '&' 和 ';'）
+func decodeEntity(name string) (string, bool) {
+	if value, ok := xmlEntities[name]; ok {
+		return value, true
+	}
+	if !strings.HasPrefix(name, "#") {
+		return "", false
+	}
+
+	var code uint64
+	var err error
+	if strings.HasPrefix(name, "#x") || strings.HasPrefix(name, "#X") {
+		code, err = strconv.ParseUint(name[2:], 16, 32)
+	} else {
+		code, err = strconv.ParseUint(name[1:], 10, 32)
+	}
+	if err != nil || code == 0 || code > 0x10FFFF || (code >= 0xD800 && code <= 0xDFFF) {
+		return "", false
+	}
+	return string(rune(code)), true
+}
+
+// isEntityName 检查是否为合法的命名实体名称（不含数字字符引用）
+func isEntityName(name string) bool {
+	for i, r := range name {
+		if (i == 0 && !isIdentifierStart(r)) || !isIdentifierChar(r) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// normalizeLineEndings 将 \r\n 和单独的 \r 统一为 \n
+func normalizeLineEndings(s string) string {
+	if !strings.Contains(s, "\r") {
+		return s
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n")
+}
@@ -129,12 +129,12 @@ func testProcessingInstructionParsing(t *testing.T) {
 		t.Fatalf("expected ProcessingInstruction, got %T", node)
 	}
 
-	if pi.Target != "xml version=\"1.0\"" {
-		t.Errorf("expected target 'xml version=\"1.0\"', got %q", pi.Target)
+	if pi.Target != "xml" {
+		t.Errorf("expected target 'xml', got %q", pi.Target)
 	}
 
-	if pi.Content != "xml version=\"1.0\"" {
-		t.Errorf("expected content 'xml version=\"1.0\"', got %q", pi.Content)
+	if pi.Content != "version=\"1.0\"" {
+		t.Errorf("expected content 'version=\"1.0\"', got %q", pi.Content)
 	}
 }
 
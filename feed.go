@@ -0,0 +1,178 @@
+package markit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// feedState 增量解析（Feed/Finish）的状态
+type feedState struct {
+	input    string        // 已接收的全部输入（按配置统一行尾）
+	resume   Lexer         // 下一个未解析顶层节点起点处的词法分析器状态
+	scan     *ParserConfig // 扫描节点边界使用的配置，去除了带副作用的回调
+	heldCR   bool          // 块末尾暂缓处理的 '\r'，等待判断是否属于 \r\n
+	doc      *Document
+	finished bool
+}
+
+// Feed 追加一段输入，返回自上次调用以来完整解析的顶层节点
+// 块边界处不完整的 token（如截断的标签或属性值）会被缓存到后续输入到达后再解析；
+// 仍未闭合的顶层元素在每次 Feed 时重新扫描，词法错误在 Finish 时报告
+// 以非空输入创建的解析器会将该输入视为第一个块
+func (p *Parser) Feed(chunk string) ([]Node, error) {
+	if err := p.startFeed(); err != nil {
+		return nil, err
+	}
+	if err := p.appendChunk(chunk); err != nil {
+		return nil, err
+	}
+
+	boundary, ok := p.scanBoundary()
+	if !ok {
+		return nil, nil
+	}
+	return p.parseSegment(boundary.start, &boundary)
+}
+
+// Finish 结束增量输入，解析剩余内容并返回包含所有顶层节点的文档
+func (p *Parser) Finish() (*Document, error) {
+	if err := p.startFeed(); err != nil {
+		return nil, err
+	}
+	if p.feed.heldCR {
+		p.feed.heldCR = false
+		p.feed.input += "\n"
+	}
+
+	if _, err := p.parseSegment(len(p.feed.input), nil); err != nil {
+		return nil, err
+	}
+	p.feed.finished = true
+	return p.feed.doc, nil
+}
+
+// startFeed 初始化增量解析状态，构造阶段或之前的解析错误会被返回
+func (p *Parser) startFeed() error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.feed == nil {
+		scan := p.config.Clone()
+		scan.OnWarning = nil
+		scan.OnElement = nil
+		scan.AttributeValueDecoder = nil
+		scan.AttributeValueSink = nil
+		scan.MaxAttributeValueBytes = 0
+
+		p.feed = &feedState{
+			input:  p.lexer.input,
+			resume: *NewLexerWithConfig("", p.config),
+			scan:   scan,
+		}
+		p.stats = ParseStats{}
+	}
+	if p.feed.finished {
+		return fmt.Errorf("feed after Finish")
+	}
+	return nil
+}
+
+// appendChunk 将块追加到已接收的输入
+func (p *Parser) appendChunk(chunk string) error {
+	if p.config.NormalizeLineEndings {
+		if p.feed.heldCR {
+			chunk = "\r" + chunk
+			p.feed.heldCR = false
+		}
+		if strings.HasSuffix(chunk, "\r") {
+			chunk = chunk[:len(chunk)-1]
+			p.feed.heldCR = true
+		}
+		chunk = normalizeLineEndings(chunk)
+	}
+	p.feed.input += chunk
+
+	if max := p.config.MaxInputBytes; max > 0 && len(p.feed.input) > max {
+		p.err = fmt.Errorf("%w: fed input exceeds limit of %d bytes", ErrInputTooLarge, max)
+		return p.err
+	}
+	return nil
+}
+
+// scanBoundary 从上次解析结束处扫描 token，返回最后一个确认完整的顶层节点之后的词法分析器状态
+// 只有当其后的 token 也被成功读取时，顶层节点才被视为完整
+func (p *Parser) scanBoundary() (Lexer, bool) {
+	l := p.feed.resumeLexer(p.feed.input)
+	l.config = p.feed.scan
+
+	var boundary, candidate Lexer
+	found, pending := false, false
+	depth := 0
+	prevVoid := ""
+	for {
+		token := l.NextToken()
+		if token.Type == TokenEOF || token.Type == TokenError {
+			return boundary, found
+		}
+
+		// 开启 AllowVoidElementClose 时，void element 之后紧跟的同名结束标签属于该元素
+		voidClose := p.config.AllowVoidElementClose && token.Type == TokenCloseTag && prevVoid != "" && p.config.namesEqual(token.Value, prevVoid)
+		if pending && !voidClose {
+			boundary, found = candidate, true
+		}
+		pending = false
+
+		prevVoid = ""
+		switch token.Type {
+		case TokenOpenTag:
+			if p.config.IsVoidElement(token.Value) {
+				prevVoid = token.Value
+			} else {
+				depth++
+			}
+		case TokenCloseTag:
+			if !voidClose {
+				depth--
+			}
+		}
+
+		if depth <= 0 {
+			candidate, pending = *l, true
+		}
+	}
+}
+
+// resumeLexer 返回从上次解析结束处继续读取 input 的词法分析器
+func (f *feedState) resumeLexer(input string) *Lexer {
+	l := f.resume
+	l.input = input
+	if l.current == 0 {
+		l.readChar()
+	}
+	return &l
+}
+
+// parseSegment 解析 [上次解析结束处, end) 区间内的顶层节点并追加到文档
+// next 为 end 处的词法分析器状态，作为下一次解析的起点
+func (p *Parser) parseSegment(end int, next *Lexer) ([]Node, error) {
+	f := p.feed
+	p.lexer = f.resumeLexer(f.input[:end])
+	leading := f.doc == nil
+	p.nextToken()
+	p.nextToken()
+	if leading {
+		f.doc = &Document{Children: []Node{}, Pos: p.current.Position}
+	}
+
+	before := len(f.doc.Children)
+	if err := p.parseInto(f.doc, leading); err != nil {
+		p.err = err
+		return nil, err
+	}
+
+	if next != nil {
+		f.resume = *next
+		f.resume.config = p.config
+	}
+	return f.doc.Children[before:], nil
+}
@@ -0,0 +1,124 @@
+package markit
+
+import (
+	"strings"
+	"testing"
+)
+
+// elementPositions 按文档顺序收集元素位置
+func elementPositions(doc *Document) []Position {
+	var positions []Position
+	_ = WalkElements(doc, func(e *Element) error {
+		positions = append(positions, e.Pos)
+		return nil
+	})
+	return positions
+}
+
+// TestFeed 测试分块增量解析
+func TestFeed(t *testing.T) {
+	input := "<!--head--><config version=\"1.0\">\n  <item id=\"a\" label='x y'>text</item>\n  <empty/>\n</config>\n<second><br></br>日本</second>tail"
+
+	config := HTMLConfig()
+	config.CaseSensitive = true
+	config.AllowVoidElementClose = true
+	expected, err := NewParserWithConfig(input, config).Parse()
+	if err != nil {
+		t.Fatalf("single-shot parse error: %v", err)
+	}
+
+	t.Run("every split point", func(t *testing.T) {
+		for split := 0; split <= len(input); split++ {
+			parser := NewParserWithConfig("", config)
+			if _, err := parser.Feed(input[:split]); err != nil {
+				t.Fatalf("split %d: feed error: %v", split, err)
+			}
+			if _, err := parser.Feed(input[split:]); err != nil {
+				t.Fatalf("split %d: feed error: %v", split, err)
+			}
+			doc, err := parser.Finish()
+			if err != nil {
+				t.Fatalf("split %d: finish error: %v", split, err)
+			}
+			if diffs := Diff(expected, doc); len(diffs) > 0 {
+				t.Fatalf("split %d: trees differ: %v", split, diffs)
+			}
+			want, got := elementPositions(expected), elementPositions(doc)
+			for i := range want {
+				if want[i] != got[i] {
+					t.Fatalf("split %d: element %d at %s, want %s", split, i, got[i], want[i])
+				}
+			}
+		}
+	})
+
+	t.Run("nodes returned as they complete", func(t *testing.T) {
+		parser := NewParserWithConfig("", config)
+		chunks := []string{`<config version="1`, `.0"><item id="a" la`, `bel='x y'>text</item>`, "</config>\n<sec", "ond>", "</second>"}
+
+		var completed []string
+		for _, chunk := range chunks {
+			nodes, err := parser.Feed(chunk)
+			if err != nil {
+				t.Fatalf("feed error: %v", err)
+			}
+			for _, node := range nodes {
+				completed = append(completed, node.String())
+			}
+		}
+		if got := strings.Join(completed, ","); got != "config" {
+			t.Errorf("expected only <config> complete before Finish, got %q", got)
+		}
+
+		doc, err := parser.Finish()
+		if err != nil {
+			t.Fatalf("finish error: %v", err)
+		}
+		if len(doc.Children) != 2 || doc.Children[1].(*Element).TagName != "second" {
+			t.Errorf("unexpected document children %v", doc.Children)
+		}
+		if doc.Children[0].(*Element).Parent != doc {
+			t.Error("fed node not attached to document")
+		}
+	})
+
+	t.Run("errors reported", func(t *testing.T) {
+		parser := NewParser("")
+		if _, err := parser.Feed("<a></b><c>"); err == nil {
+			t.Fatal("expected mismatched tag error from Feed")
+		}
+		if _, err := parser.Finish(); err == nil {
+			t.Error("expected error to persist in Finish")
+		}
+
+		parser = NewParser("")
+		if _, err := parser.Feed(`<a title="x`); err != nil {
+			t.Fatalf("truncated token should not fail Feed: %v", err)
+		}
+		if _, err := parser.Finish(); err == nil {
+			t.Error("expected unterminated value error from Finish")
+		}
+	})
+
+	t.Run("line endings split across chunks", func(t *testing.T) {
+		config := DefaultConfig()
+		config.NormalizeLineEndings = true
+		config.TrimWhitespace = false
+		parser := NewParserWithConfig("", config)
+		for _, chunk := range []string{"<a>x\r", "\ny</a><b/>"} {
+			if _, err := parser.Feed(chunk); err != nil {
+				t.Fatalf("feed error: %v", err)
+			}
+		}
+		doc, err := parser.Finish()
+		if err != nil {
+			t.Fatalf("finish error: %v", err)
+		}
+		if text := doc.Children[0].(*Element).Children[0].(*Text).Content; text != "x\ny" {
+			t.Errorf("expected single newline, got %q", text)
+		}
+		if pos := doc.Children[1].Position(); pos.Line != 2 {
+			t.Errorf("expected <b> on line 2, got %s", pos)
+		}
+	})
+}
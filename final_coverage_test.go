@@ -34,8 +34,8 @@ func TestParseNodeSpecialTokenTypes(t *testing.T) {
 			t.Fatalf("expected ProcessingInstruction, got %T", node)
 		}
 
-		if pi.Content != "xml version=\"1.0\"" {
-			t.Errorf("expected content 'xml version=\"1.0\"', got %q", pi.Content)
+		if pi.Content != "version=\"1.0\"" {
+			t.Errorf("expected content 'version=\"1.0\"', got %q", pi.Content)
 		}
 	})
 
@@ -2,6 +2,7 @@ package markit
 
 import (
 	"fmt"
+	"io"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -11,10 +12,14 @@ import (
 type Lexer struct {
 	input    string
 	position int
+	start    int // 当前字符的起始偏移
+	runes    int // 已读取的字符数，与 position 对应的字符偏移
 	line     int
 	column   int
 	current  rune
 	config   *ParserConfig
+
+	invalidUTF8 *Position // 第一个非法 UTF-8 字节的位置（仅 ValidateUTF8 时记录）
 }
 
 // NewLexer 创建新的词法分析器（使用默认配置）
@@ -24,6 +29,10 @@ func NewLexer(input string) *Lexer {
 
 // NewLexerWithConfig 创建带配置的词法分析器
 func NewLexerWithConfig(input string, config *ParserConfig) *Lexer {
+	if config != nil && config.NormalizeLineEndings {
+		input = normalizeLineEndings(input)
+	}
+
 	l := &Lexer{
 		input:  input,
 		line:   1,
@@ -51,26 +60,19 @@ func (l *Lexer) NextToken() Token {
 		l.skipWhitespace()
 	}
 
-	pos := Position{
-		Line:   l.line,
-		Column: l.column,
-		Offset: l.position,
+	// 输入中出现非法 UTF-8 时，从读到该字节之后的下一个 token 开始报错
+	if l.invalidUTF8 != nil {
+		return Token{Type: TokenError, Value: "invalid UTF-8 encoding", Position: *l.invalidUTF8}
 	}
 
+	pos := l.currentPosition()
+
 	if l.position >= len(l.input) {
 		return Token{Type: TokenEOF, Value: "", Position: pos}
 	}
 
-	// 计算当前字符的位置（因为 readChar 已经移动了位置）
-	currentPos := l.position
-	if l.current != 0 {
-		// 回退到当前字符的位置
-		_, size := utf8.DecodeRuneInString(l.input[l.position-1:])
-		currentPos = l.position - size
-	}
-
 	// 使用核心协议匹配器检查是否是标签开始
-	if protocol := l.config.CoreMatcher.MatchProtocol(l.input, currentPos); protocol != nil {
+	if protocol := l.config.CoreMatcher.MatchProtocol(l.input, l.currentOffset()); protocol != nil {
 		return l.readProtocolToken(protocol)
 	}
 
@@ -83,19 +85,48 @@ func (l *Lexer) NextToken() Token {
 func (l *Lexer) readChar() {
 	if l.position >= len(l.input) {
 		l.current = 0 // EOF
+		l.start = l.position
 	} else {
 		if l.current == '\n' {
 			l.line++
 			l.column = 0
 		}
-		// 正确解码UTF-8字符
-		r, size := utf8.DecodeRuneInString(l.input[l.position:])
-		l.current = r
-		l.position += size
+		l.start = l.position
+		// ASCII 快速路径，其余正确解码UTF-8字符
+		invalid := false
+		if b := l.input[l.position]; b < utf8.RuneSelf {
+			l.current = rune(b)
+			l.position++
+		} else {
+			r, size := utf8.DecodeRuneInString(l.input[l.position:])
+			l.current = r
+			l.position += size
+			invalid = r == utf8.RuneError && size == 1
+		}
+		l.runes++
 		l.column++
+
+		if invalid && l.invalidUTF8 == nil && l.config != nil && l.config.ValidateUTF8 {
+			pos := l.currentPosition()
+			l.invalidUTF8 = &pos
+		}
 	}
 }
 
+// currentPosition 返回当前字符的位置，UseRuneOffsets 开启时 Offset 为字符偏移
+func (l *Lexer) currentPosition() Position {
+	pos := Position{
+		Line:       l.line,
+		Column:     l.column,
+		Offset:     l.position,
+		ByteOffset: l.position,
+	}
+	if l.config != nil && l.config.UseRuneOffsets {
+		pos.Offset = l.runes
+	}
+	return pos
+}
+
 // peekChar 查看下一个字符但不移动位置
 func (l *Lexer) peekChar() rune {
 	if l.position >= len(l.input) {
@@ -108,16 +139,79 @@ func (l *Lexer) peekChar() rune {
 
 // skipWhitespace 跳过空白字符
 func (l *Lexer) skipWhitespace() {
-	for unicode.IsSpace(l.current) {
+	// 未自定义空白字符时，ASCII 字符走快速路径
+	if l.config == nil || l.config.IsWhitespace == nil {
+		for {
+			switch l.current {
+			case ' ', '\t', '\n', '\r', '\v', '\f':
+				l.readChar()
+				continue
+			}
+			if l.current < utf8.RuneSelf || !unicode.IsSpace(l.current) {
+				return
+			}
+			l.readChar()
+		}
+	}
+
+	for l.isWhitespace(l.current) {
 		l.readChar()
 	}
 }
 
+// isWhitespace 根据配置判断字符是否为空白字符（默认使用 unicode.IsSpace）
+func (l *Lexer) isWhitespace(r rune) bool {
+	if r == 0 {
+		return false
+	}
+	if l.config != nil && l.config.IsWhitespace != nil {
+		return l.config.IsWhitespace(r)
+	}
+	return unicode.IsSpace(r)
+}
+
+// trimWhitespace 根据配置修剪字符串首尾的空白字符
+func (l *Lexer) trimWhitespace(s string) string {
+	return strings.TrimFunc(s, l.isWhitespace)
+}
+
+// currentOffset 返回当前字符在输入中的起始偏移
+func (l *Lexer) currentOffset() int {
+	return l.start
+}
+
+// atSeq 检查输入是否从当前字符开始匹配指定序列
+func (l *Lexer) atSeq(seq string) bool {
+	return l.current != 0 && strings.HasPrefix(l.input[l.currentOffset():], seq)
+}
+
+// tagDelimiters 返回标准标签的开始和结束序列
+func (l *Lexer) tagDelimiters() (string, string) {
+	if l.config != nil && l.config.CoreMatcher != nil {
+		if tag := l.config.CoreMatcher.StandardTag(); tag != nil {
+			return tag.OpenSeq, tag.CloseSeq
+		}
+	}
+	return "<", ">"
+}
+
+// atProtocolStart 检查当前字符是否开始一个核心协议
+func (l *Lexer) atProtocolStart() bool {
+	if l.config == nil || l.config.CoreMatcher == nil {
+		return l.current == '<'
+	}
+	matcher := l.config.CoreMatcher
+	if l.current == 0 || !matcher.CanStart(l.input[l.start]) {
+		return false
+	}
+	return matcher.MatchProtocol(l.input, l.start) != nil
+}
+
 // readText 读取文本内容
 func (l *Lexer) readText(pos Position) Token {
 	var text strings.Builder
 
-	for l.current != '<' && l.current != 0 {
+	for l.current != 0 && !l.atProtocolStart() {
 		text.WriteRune(l.current)
 		l.readChar()
 	}
@@ -126,13 +220,17 @@ func (l *Lexer) readText(pos Position) Token {
 
 	// 根据配置决定是否修剪空白字符
 	if l.config != nil && l.config.TrimWhitespace {
-		content = strings.TrimSpace(content)
+		content = l.trimWhitespace(content)
 		// 如果修剪后内容为空，跳过这个token
 		if content == "" {
 			return l.NextToken() // 递归获取下一个token
 		}
 	}
 
+	if l.config != nil && l.config.DecodeEntities {
+		content = decodeEntitiesWith(content, l.config.OnUnknownEntity)
+	}
+
 	return Token{
 		Type:     TokenText,
 		Value:    content,
@@ -140,6 +238,23 @@ func (l *Lexer) readText(pos Position) Token {
 	}
 }
 
+// skipNamelessAttribute 跳过缺少属性名的 '=' 及其后可能存在的值
+func (l *Lexer) skipNamelessAttribute(closeSeq string) {
+	l.readChar() // 跳过 '='
+	l.skipWhitespace()
+	if l.current != 0 && l.current != '/' && !l.atSeq(closeSeq) {
+		_, _ = l.readAttributeValue()
+	}
+	l.skipWhitespace()
+}
+
+// warn 通过 OnWarning 回调报告可恢复的问题
+func (l *Lexer) warn(pos Position, message string) {
+	if l.config != nil && l.config.OnWarning != nil {
+		l.config.OnWarning(pos, message)
+	}
+}
+
 // readIdentifier 读取标识符（标签名或属性名）
 func (l *Lexer) readIdentifier() string {
 	var identifier strings.Builder
@@ -157,12 +272,13 @@ func (l *Lexer) readIdentifier() string {
 	return identifier.String()
 }
 
-// readAttribute 读取属性
-func (l *Lexer) readAttribute() (string, string, error) {
+// readAttribute 读取属性，hasValue 表示属性是否带有 '=' 赋值，
+// decoded 表示值经过实体解码且源码中没有未转义的 '&'
+func (l *Lexer) readAttribute() (name string, value string, hasValue bool, decoded bool, err error) {
 	// 读取属性名
-	name := l.readIdentifier()
+	name = l.readIdentifier()
 	if name == "" {
-		return "", "", fmt.Errorf("invalid attribute name")
+		return "", "", false, false, fmt.Errorf("invalid attribute name")
 	}
 
 	l.skipWhitespace()
@@ -170,31 +286,64 @@ func (l *Lexer) readAttribute() (string, string, error) {
 	// 检查是否有等号
 	if l.current != '=' {
 		// 布尔属性，没有值
-		return name, "", nil
+		return name, "", false, false, nil
 	}
 
 	l.readChar() // 跳过 '='
 	l.skipWhitespace()
 
+	// 超过大小限制的属性值交给 AttributeValueSink 流式处理
+	if l.config != nil && l.config.MaxAttributeValueBytes > 0 {
+		if start, end, ok := l.scanAttributeValue(); ok && end-start > l.config.MaxAttributeValueBytes {
+			if l.config.AttributeValueSink == nil {
+				return "", "", false, false, fmt.Errorf("value of attribute %q exceeds limit of %d bytes", name, l.config.MaxAttributeValueBytes)
+			}
+			l.streamAttributeValue(name, start, end)
+			return name, StreamedAttributeValue, true, false, nil
+		}
+	}
+
 	// 读取属性值
-	value, err := l.readAttributeValue()
+	value, err = l.readAttributeValue()
 	if err != nil {
-		return "", "", err
+		return "", "", false, false, err
+	}
+	// 在解码前修剪，保留以字符引用编码的空白
+	if l.config != nil && l.config.TrimAttributeValues {
+		value = l.trimWhitespace(value)
+	}
+	switch {
+	case l.config != nil && l.config.AttributeValueDecoder != nil:
+		value, err = l.config.AttributeValueDecoder(name, value)
+		if err != nil {
+			return "", "", false, false, fmt.Errorf("invalid value for attribute %q: %w", name, err)
+		}
+	case l.config != nil && l.config.DecodeEntities:
+		decoded = !hasRawAmpersand(value)
+		value = decodeEntitiesWith(value, l.config.OnUnknownEntity)
 	}
 
-	return name, value, nil
+	return name, value, true, decoded, nil
+}
+
+// isAttributeQuote 判断字符是否为属性值的引号，反引号需开启 AllowBacktickAttributes
+func (l *Lexer) isAttributeQuote(r rune) bool {
+	if r == '`' {
+		return l.config != nil && l.config.AllowBacktickAttributes
+	}
+	return r == '"' || r == '\''
 }
 
 // readAttributeValue 读取属性值
 func (l *Lexer) readAttributeValue() (string, error) {
-	if l.current == '"' || l.current == '\'' {
-		// 带引号的值
+	if l.isAttributeQuote(l.current) {
+		// 带引号的值，反引号内容不处理反斜杠转义
 		quote := l.current
 		l.readChar() // 跳过开始引号
 
 		var value strings.Builder
 		for l.current != quote && l.current != 0 {
-			if l.current == '\\' {
+			if l.current == '\\' && quote != '`' {
 				l.readChar()
 				if l.current != 0 {
 					value.WriteRune(l.current)
@@ -214,8 +363,9 @@ func (l *Lexer) readAttributeValue() (string, error) {
 		return value.String(), nil
 	} else {
 		// 不带引号的值
+		_, closeSeq := l.tagDelimiters()
 		var value strings.Builder
-		for !unicode.IsSpace(l.current) && l.current != '>' && l.current != '/' && l.current != 0 {
+		for !l.isWhitespace(l.current) && !l.atSeq(closeSeq) && l.current != '/' && l.current != 0 {
 			value.WriteRune(l.current)
 			l.readChar()
 		}
@@ -223,6 +373,82 @@ func (l *Lexer) readAttributeValue() (string, error) {
 	}
 }
 
+// StreamedAttributeValue 属性值交给 AttributeValueSink 后在 AST 中保存的占位值
+const StreamedAttributeValue = "[streamed]"
+
+// scanAttributeValue 在不移动位置的情况下查找当前属性值的原始字节区间（不含引号）
+// 引号未闭合时返回 false，交由 readAttributeValue 报告错误
+func (l *Lexer) scanAttributeValue() (start, end int, ok bool) {
+	if l.isAttributeQuote(l.current) {
+		quote := l.input[l.start]
+		start = l.start + 1
+		for i := start; i < len(l.input); i++ {
+			switch l.input[i] {
+			case '\\':
+				if quote != '`' {
+					i++
+				}
+			case quote:
+				return start, i, true
+			}
+		}
+		return 0, 0, false
+	}
+
+	_, closeSeq := l.tagDelimiters()
+	start = l.start
+	for end = start; end < len(l.input); {
+		r, size := utf8.DecodeRuneInString(l.input[end:])
+		if l.isWhitespace(r) || r == '/' || strings.HasPrefix(l.input[end:], closeSeq) {
+			break
+		}
+		end += size
+	}
+	return start, end, true
+}
+
+// streamAttributeValue 将 [start, end) 区间的属性值传给 AttributeValueSink 并跳过该值
+func (l *Lexer) streamAttributeValue(name string, start, end int) {
+	quoted := start > l.start
+	unescape := quoted && l.input[l.start] != '`'
+	l.config.AttributeValueSink(name, &attributeValueReader{input: l.input[start:end], unescape: unescape})
+
+	if quoted {
+		end++ // 跳过结束引号
+	}
+	for l.current != 0 && l.currentOffset() < end {
+		l.readChar()
+	}
+}
+
+// attributeValueReader 按需读取原始属性值，带引号的值会去除反斜杠转义
+type attributeValueReader struct {
+	input    string
+	unescape bool
+}
+
+func (r *attributeValueReader) Read(p []byte) (int, error) {
+	if len(r.input) == 0 {
+		return 0, io.EOF
+	}
+	if !r.unescape {
+		n := copy(p, r.input)
+		r.input = r.input[n:]
+		return n, nil
+	}
+
+	n := 0
+	for n < len(p) && len(r.input) > 0 {
+		if r.input[0] == '\\' && len(r.input) > 1 {
+			r.input = r.input[1:]
+		}
+		p[n] = r.input[0]
+		r.input = r.input[1:]
+		n++
+	}
+	return n, nil
+}
+
 // isIdentifierStart 检查字符是否可以作为标识符的开始
 func isIdentifierStart(r rune) bool {
 	return unicode.IsLetter(r) || r == '_' || r == '-' || r == ':'
@@ -241,9 +467,16 @@ func (l *Lexer) readComment(pos Position) Token {
 	}
 
 	var comment strings.Builder
+	maxLength := 0
+	if l.config != nil {
+		maxLength = l.config.MaxCommentLength
+	}
 
 	// 读取注释内容直到找到 -->
 	for l.current != 0 {
+		if maxLength > 0 && comment.Len() > maxLength {
+			return Token{Type: TokenError, Value: fmt.Sprintf("comment exceeds limit of %d bytes", maxLength), Position: pos}
+		}
 		if l.current == '-' && l.peekChar() == '-' {
 			// 检查是否是注释结束
 			l.readChar() // 跳过第一个 '-'
@@ -261,11 +494,14 @@ func (l *Lexer) readComment(pos Position) Token {
 		}
 	}
 
+	if maxLength > 0 && comment.Len() > maxLength {
+		return Token{Type: TokenError, Value: fmt.Sprintf("comment exceeds limit of %d bytes", maxLength), Position: pos}
+	}
 	commentContent := comment.String()
 
 	// 根据配置决定是否修剪空白字符
-	if l.config != nil && l.config.TrimWhitespace {
-		commentContent = strings.TrimSpace(commentContent)
+	if l.config != nil && l.config.shouldTrimComment() {
+		commentContent = l.trimWhitespace(commentContent)
 	}
 
 	return Token{
@@ -275,16 +511,117 @@ func (l *Lexer) readComment(pos Position) Token {
 	}
 }
 
+// CDATA 区段的开始和结束序列
+const (
+	cdataOpenSeq  = "<![CDATA["
+	cdataCloseSeq = "]]>"
+)
+
+// readCDATA 读取 CDATA 区段 <![CDATA[ ... ]]>，内容保持原样
+func (l *Lexer) readCDATA(pos Position) Token {
+	for range cdataOpenSeq {
+		l.readChar()
+	}
+
+	start := l.currentOffset()
+	end := strings.Index(l.input[start:], cdataCloseSeq)
+	if end < 0 {
+		// 未终止的 CDATA 读取到文件末尾
+		for l.current != 0 {
+			l.readChar()
+		}
+		return Token{Type: TokenCDATA, Value: l.input[start:], Position: pos}
+	}
+
+	for l.currentOffset() < start+end+len(cdataCloseSeq) {
+		l.readChar()
+	}
+
+	return Token{
+		Type:     TokenCDATA,
+		Value:    l.input[start : start+end],
+		Position: pos,
+	}
+}
+
+// doctypeOpenSeq DOCTYPE 声明的开始序列（不区分大小写）
+const doctypeOpenSeq = "<!DOCTYPE"
+
+// atDoctype 检查当前位置是否为 DOCTYPE 声明的开始
+func (l *Lexer) atDoctype() bool {
+	rest := l.input[l.currentOffset():]
+	return l.current != 0 && len(rest) >= len(doctypeOpenSeq) && strings.EqualFold(rest[:len(doctypeOpenSeq)], doctypeOpenSeq)
+}
+
+// readDoctype 读取 DOCTYPE 声明，内部子集 [...] 和引号内的 '>' 不会结束声明
+// token 的值为 <!DOCTYPE 与 > 之间去除首尾空白的内容
+func (l *Lexer) readDoctype(pos Position) Token {
+	for range doctypeOpenSeq {
+		l.readChar()
+	}
+
+	start := l.currentOffset()
+	end := doctypeEnd(l.input, start)
+	if end < 0 {
+		return Token{Type: TokenError, Value: "unterminated DOCTYPE declaration", Position: pos}
+	}
+
+	for l.currentOffset() <= end {
+		l.readChar()
+	}
+
+	return Token{
+		Type:     TokenDoctype,
+		Value:    strings.TrimSpace(l.input[start:end]),
+		Position: pos,
+	}
+}
+
+// 处理指令的开始和结束序列
+const (
+	piOpenSeq  = "<?"
+	piCloseSeq = "?>"
+)
+
+// readProcessingInstruction 读取处理指令 <?target content?>
+// token 的值为 <? 与 ?> 之间的原始内容，目标和内容由解析器拆分
+func (l *Lexer) readProcessingInstruction(pos Position) Token {
+	for range piOpenSeq {
+		l.readChar()
+	}
+
+	start := l.currentOffset()
+	end := strings.Index(l.input[start:], piCloseSeq)
+	if end < 0 {
+		return Token{Type: TokenError, Value: "unterminated processing instruction", Position: pos}
+	}
+
+	for l.currentOffset() < start+end+len(piCloseSeq) {
+		l.readChar()
+	}
+
+	return Token{
+		Type:     TokenProcessingInstruction,
+		Value:    l.input[start : start+end],
+		Position: pos,
+	}
+}
+
 // readProtocolToken 读取协议token
 func (l *Lexer) readProtocolToken(protocol *CoreProtocol) Token {
-	pos := Position{
-		Line:   l.line,
-		Column: l.column,
-		Offset: l.position,
-	}
+	pos := l.currentPosition()
 
 	if protocol.Name == "markit-standard-tag" {
-		return l.readTag(pos)
+		if l.atSeq(cdataOpenSeq) {
+			return l.readCDATA(pos)
+		}
+		if l.atDoctype() {
+			return l.readDoctype(pos)
+		}
+		if l.atSeq(piOpenSeq) {
+			return l.readProcessingInstruction(pos)
+		}
+		return l.readTag(pos, protocol)
 	} else if protocol.Name == "markit-comment" {
 		return l.readComment(pos)
 	}
@@ -321,34 +658,119 @@ func (l *Lexer) readProtocolToken(protocol *CoreProtocol) Token {
 }
 
 // readTag 读取标签
-func (l *Lexer) readTag(pos Position) Token {
-	l.readChar() // 跳过 '<'
+func (l *Lexer) readTag(pos Position, protocol *CoreProtocol) Token {
+	openSeq, closeSeq := protocol.OpenSeq, protocol.CloseSeq
+
+	// 跳过开始序列（如 '<'）
+	for range openSeq {
+		l.readChar()
+	}
 
 	// 检查是否是结束标签
 	isCloseTag := false
+	lenientClose := l.config != nil && l.config.LenientCloseTags
 	if l.current == '/' {
 		isCloseTag = true
 		l.readChar() // 跳过 '/'
+		if lenientClose {
+			l.skipWhitespace()
+		}
 	}
 
 	// 读取标签名
+	var namePos Position
+	if isCloseTag && l.config != nil && l.config.TrackCloseTagPositions {
+		namePos = l.currentPosition()
+	}
 	tagName := l.readIdentifier()
+	// 开始和结束标签使用相同的重写规则，保证重写后仍能匹配；
+	// 重写函数收到按 CaseSensitive 规范化后的名称，未被改写的标签保留原始大小写
+	if tagName != "" && l.config != nil && l.config.TagNameRewriter != nil {
+		normalized := l.config.NormalizeCase(tagName)
+		if rewritten := l.config.TagNameRewriter(normalized); rewritten != normalized {
+			tagName = rewritten
+		}
+	}
 	if tagName == "" {
-		return Token{Type: TokenError, Value: "invalid tag name", Position: pos}
+		if !l.atSeq(closeSeq) {
+			return Token{Type: TokenError, Value: "invalid tag name", Position: pos}
+		}
+		// <> 和 </> 仅在 AllowEmptyTags 开启时作为片段标签，</> 在 AllowAnonymousClose 开启时闭合当前元素
+		anonymousClose := isCloseTag && l.config != nil && l.config.AllowAnonymousClose
+		if !anonymousClose && (l.config == nil || !l.config.AllowEmptyTags) {
+			slash := ""
+			if isCloseTag {
+				slash = "/"
+			}
+			return Token{Type: TokenError, Value: fmt.Sprintf("empty tag %s%s%s", openSeq, slash, closeSeq), Position: pos}
+		}
 	}
 
-	// 跳过空白
+	// 跳过空白（结束标签名之后的空白在 XML 中同样合法）
 	l.skipWhitespace()
 
-	// 读取属性
-	attributes := make(map[string]string)
+	// 读取属性，映射在遇到第一个属性时才分配，无属性的标签保持 nil
+	var attributes map[string]string
+	var attributeOrder []string
+	var emptyAttributes map[string]bool
+	var decodedAttributes map[string]bool
+	var attributePositions map[string]Position
+	trackPositions := l.config != nil && l.config.TrackAttributePositions
 	if !isCloseTag {
-		for l.current != '>' && l.current != '/' && l.current != 0 {
-			name, value, err := l.readAttribute()
+		for !l.atSeq(closeSeq) && l.current != '/' && l.current != 0 {
+			attrPos := l.currentPosition()
+			if l.current == '=' {
+				message := fmt.Sprintf("empty attribute name in <%s> at column %d", tagName, attrPos.Column)
+				if l.config == nil || !l.config.LenientAttributes {
+					return Token{Type: TokenError, Value: message, Position: attrPos}
+				}
+				// 宽松模式下跳过 '=' 及其后的值
+				l.warn(attrPos, message)
+				l.skipNamelessAttribute(closeSeq)
+				continue
+			}
+			name, value, hasValue, decoded, err := l.readAttribute()
 			if err != nil {
-				return Token{Type: TokenError, Value: err.Error(), Position: pos}
+				if l.config == nil || !l.config.LenientAttributes {
+					return Token{Type: TokenError, Value: err.Error(), Position: pos}
+				}
+				// 宽松模式下跳过错误属性，继续读取标签的其余部分
+				l.warn(attrPos, fmt.Sprintf("skipping malformed attribute in <%s>: %v", tagName, err))
+				for l.current != 0 && !l.isWhitespace(l.current) && !l.atSeq(closeSeq) && !l.atSeq("/"+closeSeq) {
+					l.readChar()
+				}
+				l.skipWhitespace()
+				continue
+			}
+			if hasValue && value == "" {
+				if emptyAttributes == nil {
+					emptyAttributes = make(map[string]bool)
+				}
+				emptyAttributes[name] = true
+			} else if emptyAttributes != nil {
+				delete(emptyAttributes, name)
+			}
+			if decoded {
+				if decodedAttributes == nil {
+					decodedAttributes = make(map[string]bool)
+				}
+				decodedAttributes[name] = true
+			} else if decodedAttributes != nil {
+				delete(decodedAttributes, name)
+			}
+			if attributes == nil {
+				attributes = make(map[string]string, 1)
+			}
+			if _, exists := attributes[name]; !exists {
+				attributeOrder = append(attributeOrder, name)
 			}
 			attributes[name] = value
+			if trackPositions {
+				if attributePositions == nil {
+					attributePositions = make(map[string]Position, 1)
+				}
+				attributePositions[name] = attrPos
+			}
 			l.skipWhitespace()
 		}
 	}
@@ -367,11 +789,13 @@ func (l *Lexer) readTag(pos Position) Token {
 		}
 	}
 
-	// 跳过 '>'
-	if l.current != '>' {
-		return Token{Type: TokenError, Value: "expected '>'", Position: pos}
+	// 跳过结束序列（如 '>'）
+	if !l.atSeq(closeSeq) {
+		return Token{Type: TokenError, Value: fmt.Sprintf("expected '%s'", closeSeq), Position: pos}
+	}
+	for range closeSeq {
+		l.readChar()
 	}
-	l.readChar()
 
 	// 确定token类型
 	var tokenType TokenType
@@ -384,9 +808,14 @@ func (l *Lexer) readTag(pos Position) Token {
 	}
 
 	return Token{
-		Type:       tokenType,
-		Value:      tagName,
-		Attributes: attributes,
-		Position:   pos,
+		Type:               tokenType,
+		Value:              tagName,
+		Attributes:         attributes,
+		AttributeOrder:     attributeOrder,
+		EmptyAttributes:    emptyAttributes,
+		DecodedAttributes:  decodedAttributes,
+		AttributePositions: attributePositions,
+		NamePos:            namePos,
+		Position:           pos,
 	}
 }
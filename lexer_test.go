@@ -1,6 +1,8 @@
 package markit
 
 import (
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -379,3 +381,445 @@ func TestLexerConfigurationEffects(t *testing.T) {
 		t.Errorf("expected self-close tag token when enabled, got %v", token.Type)
 	}
 }
+
+// TestLexerAttributePositions 测试属性位置记录
+func TestLexerAttributePositions(t *testing.T) {
+	t.Run("columns of multiple attributes", func(t *testing.T) {
+		config := DefaultConfig()
+		config.TrackAttributePositions = true
+
+		input := `<img src="a.png" alt='pic' hidden width=10/>`
+		token := NewLexerWithConfig(input, config).NextToken()
+		if token.Type != TokenSelfCloseTag {
+			t.Fatalf("expected SELF_CLOSE_TAG, got %v", token.Type)
+		}
+
+		expected := map[string]int{
+			"src":    6,
+			"alt":    18,
+			"hidden": 28,
+			"width":  35,
+		}
+		if len(token.AttributePositions) != len(expected) {
+			t.Fatalf("expected %d positions, got %d", len(expected), len(token.AttributePositions))
+		}
+		for name, column := range expected {
+			pos, ok := token.AttributePositions[name]
+			if !ok {
+				t.Errorf("missing position for %s", name)
+				continue
+			}
+			if pos.Line != 1 || pos.Column != column {
+				t.Errorf("attribute %s: expected 1:%d, got %s", name, column, pos)
+			}
+		}
+	})
+
+	t.Run("attributes across lines", func(t *testing.T) {
+		config := DefaultConfig()
+		config.TrackAttributePositions = true
+
+		token := NewLexerWithConfig("<div\n  id=\"a\"\n  class=\"b\">", config).NextToken()
+		if pos := token.AttributePositions["id"]; pos.Line != 2 || pos.Column != 3 {
+			t.Errorf("id: expected 2:3, got %s", pos)
+		}
+		if pos := token.AttributePositions["class"]; pos.Line != 3 || pos.Column != 3 {
+			t.Errorf("class: expected 3:3, got %s", pos)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		token := NewLexer(`<div id="a">`).NextToken()
+		if token.AttributePositions != nil {
+			t.Errorf("expected nil positions, got %v", token.AttributePositions)
+		}
+	})
+}
+
+// TestLexerCustomWhitespace 测试自定义空白字符定义
+func TestLexerCustomWhitespace(t *testing.T) {
+	asciiSpace := func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}
+
+	t.Run("NBSP survives into text content", func(t *testing.T) {
+		config := DefaultConfig()
+		config.IsWhitespace = asciiSpace
+
+		doc, err := NewParserWithConfig("<p> \u00A0content\u00A0 </p>", config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		text := doc.Children[0].(*Element).Children[0].(*Text)
+		if text.Content != "\u00A0content\u00A0" {
+			t.Errorf("expected NBSP to be preserved, got %q", text.Content)
+		}
+	})
+
+	t.Run("NBSP-only text is not skipped", func(t *testing.T) {
+		config := DefaultConfig()
+		config.IsWhitespace = asciiSpace
+
+		lexer := NewLexerWithConfig("<a>\u00A0</a>", config)
+		lexer.NextToken()
+		token := lexer.NextToken()
+		if token.Type != TokenText || token.Value != "\u00A0" {
+			t.Errorf("expected NBSP text token, got %v", token)
+		}
+	})
+
+	t.Run("default treats NBSP as whitespace", func(t *testing.T) {
+		doc, err := NewParser("<p>\u00A0content\u00A0</p>").Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		text := doc.Children[0].(*Element).Children[0].(*Text)
+		if text.Content != "content" {
+			t.Errorf("expected NBSP to be trimmed, got %q", text.Content)
+		}
+	})
+}
+
+// TestLexerWhitespaceHeavyTokens 测试大量缩进和空白输入的 token 输出
+func TestLexerWhitespaceHeavyTokens(t *testing.T) {
+	input := "<root>\n    <item   id=\"x\"   >\n\t\t  text é \r\n    </item>\n    <!-- c -->\n</root>\n"
+	expected := []struct {
+		typ    TokenType
+		value  string
+		line   int
+		column int
+		offset int
+	}{
+		{TokenOpenTag, "root", 1, 1, 1},
+		{TokenOpenTag, "item", 2, 5, 12},
+		{TokenText, "text é", 3, 5, 35},
+		{TokenCloseTag, "item", 4, 5, 50},
+		{TokenComment, "c", 5, 5, 62},
+		{TokenCloseTag, "root", 6, 1, 73},
+		{TokenEOF, "", 6, 8, 80},
+	}
+
+	lexer := NewLexer(input)
+	for i, want := range expected {
+		token := lexer.NextToken()
+		if token.Type != want.typ || token.Value != want.value {
+			t.Fatalf("token %d: expected %v %q, got %v %q", i, want.typ, want.value, token.Type, token.Value)
+		}
+		if token.Position.Line != want.line || token.Position.Column != want.column || token.Position.Offset != want.offset {
+			t.Errorf("token %d: expected position %d:%d@%d, got %d:%d@%d", i,
+				want.line, want.column, want.offset,
+				token.Position.Line, token.Position.Column, token.Position.Offset)
+		}
+	}
+}
+
+// TestLexerLenientAttributes 测试宽松模式下跳过错误属性
+func TestLexerLenientAttributes(t *testing.T) {
+	input := `<div id="main" 9bad=x class="box" title="ok">`
+
+	t.Run("strict mode fails the tag", func(t *testing.T) {
+		token := NewLexer(input).NextToken()
+		if token.Type != TokenError {
+			t.Errorf("expected error token, got %v %q", token.Type, token.Value)
+		}
+	})
+
+	t.Run("lenient mode keeps good attributes", func(t *testing.T) {
+		config := DefaultConfig()
+		config.LenientAttributes = true
+		var warnings []string
+		var warningPos Position
+		config.OnWarning = func(pos Position, message string) {
+			warnings = append(warnings, message)
+			warningPos = pos
+		}
+
+		token := NewLexerWithConfig(input, config).NextToken()
+		if token.Type != TokenOpenTag || token.Value != "div" {
+			t.Fatalf("expected open tag div, got %v %q", token.Type, token.Value)
+		}
+		expected := map[string]string{"id": "main", "class": "box", "title": "ok"}
+		if len(token.Attributes) != len(expected) {
+			t.Errorf("expected %d attributes, got %v", len(expected), token.Attributes)
+		}
+		for name, value := range expected {
+			if token.Attributes[name] != value {
+				t.Errorf("expected %s=%q, got %q", name, value, token.Attributes[name])
+			}
+		}
+		if len(warnings) != 1 {
+			t.Fatalf("expected 1 warning, got %v", warnings)
+		}
+		if warningPos.Column != 16 {
+			t.Errorf("expected warning at column 16, got %d", warningPos.Column)
+		}
+	})
+
+	t.Run("bad attribute before closing bracket", func(t *testing.T) {
+		config := DefaultConfig()
+		config.LenientAttributes = true
+
+		doc, err := NewParserWithConfig(`<p a="1" @click>text</p>`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		p := doc.Children[0].(*Element)
+		if p.Attributes["a"] != "1" || len(p.Attributes) != 1 {
+			t.Errorf("unexpected attributes %v", p.Attributes)
+		}
+		if p.Children[0].(*Text).Content != "text" {
+			t.Errorf("unexpected children %v", p.Children)
+		}
+	})
+
+	t.Run("bad attribute before self-closing end", func(t *testing.T) {
+		config := DefaultConfig()
+		config.LenientAttributes = true
+
+		doc, err := NewParserWithConfig(`<r><a x="1" y="2 z="3"/><b/></r>`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		r := doc.Children[0].(*Element)
+		if len(r.Children) != 2 {
+			t.Fatalf("expected <a> to self-close before <b>, got %d children", len(r.Children))
+		}
+		a := r.Children[0].(*Element)
+		if !a.SelfClose || a.Attributes["x"] != "1" || a.Attributes["y"] != "2 z=" {
+			t.Errorf("unexpected element %+v", a)
+		}
+	})
+}
+
+// TestLexerLenientCloseTags 测试结束标签中的空白处理
+func TestLexerLenientCloseTags(t *testing.T) {
+	inputs := []string{"</ div>", "</ div\t>", "</\ndiv >"}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			token := NewLexer(input).NextToken()
+			if token.Type != TokenError {
+				t.Errorf("strict mode: expected error token, got %v %q", token.Type, token.Value)
+			}
+
+			config := DefaultConfig()
+			config.LenientCloseTags = true
+			token = NewLexerWithConfig(input, config).NextToken()
+			if token.Type != TokenCloseTag || token.Value != "div" {
+				t.Errorf("lenient mode: expected close tag div, got %v %q", token.Type, token.Value)
+			}
+		})
+	}
+
+	t.Run("parse with lenient close tags", func(t *testing.T) {
+		config := DefaultConfig()
+		config.LenientCloseTags = true
+		doc, err := NewParserWithConfig("<div><p>x</ p ></div >", config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		if doc.Children[0].(*Element).Children[0].(*Element).TagName != "p" {
+			t.Error("expected nested p element")
+		}
+		if _, err := NewParser("<div>x</ div>").Parse(); err == nil {
+			t.Error("expected strict parse error")
+		}
+	})
+
+	t.Run("trailing whitespace allowed by default", func(t *testing.T) {
+		for _, input := range []string{"</div >", "</div\t\n>"} {
+			token := NewLexer(input).NextToken()
+			if token.Type != TokenCloseTag || token.Value != "div" {
+				t.Errorf("%q: expected close tag div, got %v %q", input, token.Type, token.Value)
+			}
+		}
+		if _, err := NewParser("<div><p>x</p ></div >").Parse(); err != nil {
+			t.Errorf("unexpected parse error: %v", err)
+		}
+	})
+}
+
+// TestLexerRuneOffsets 测试字符偏移与字节偏移
+func TestLexerRuneOffsets(t *testing.T) {
+	input := "<p>日本🎉</p><b/>"
+	expected := []struct {
+		typ        TokenType
+		offset     int
+		byteOffset int
+	}{
+		{TokenOpenTag, 1, 1},
+		{TokenText, 4, 6},
+		{TokenCloseTag, 7, 14},
+		{TokenSelfCloseTag, 11, 18},
+	}
+
+	config := DefaultConfig()
+	config.UseRuneOffsets = true
+	runeLexer := NewLexerWithConfig(input, config)
+	byteLexer := NewLexer(input)
+	for i, want := range expected {
+		token := runeLexer.NextToken()
+		if token.Type != want.typ {
+			t.Fatalf("token %d: expected %v, got %v", i, want.typ, token.Type)
+		}
+		if token.Position.Offset != want.offset || token.Position.ByteOffset != want.byteOffset {
+			t.Errorf("token %d: expected offsets %d/%d, got %d/%d", i,
+				want.offset, want.byteOffset, token.Position.Offset, token.Position.ByteOffset)
+		}
+
+		token = byteLexer.NextToken()
+		if token.Position.Offset != want.byteOffset || token.Position.ByteOffset != want.byteOffset {
+			t.Errorf("token %d: expected byte offsets %d by default, got %d/%d", i,
+				want.byteOffset, token.Position.Offset, token.Position.ByteOffset)
+		}
+	}
+}
+
+// TestLexerEmptyAttributeName 测试缺少属性名的 '='
+func TestLexerEmptyAttributeName(t *testing.T) {
+	t.Run("strict error message", func(t *testing.T) {
+		token := NewLexer(`<div id="a" ="x">`).NextToken()
+		if token.Type != TokenError {
+			t.Fatalf("expected error token, got %v", token.Type)
+		}
+		if token.Value != "empty attribute name in <div> at column 13" {
+			t.Errorf("unexpected message %q", token.Value)
+		}
+		if token.Position.Line != 1 || token.Position.Column != 13 {
+			t.Errorf("expected position 1:13, got %s", token.Position)
+		}
+
+		_, err := NewParser(`<div = >text</div>`).Parse()
+		if err == nil || !strings.Contains(err.Error(), "empty attribute name in <div> at column 6") {
+			t.Errorf("expected positioned parse error, got %v", err)
+		}
+	})
+
+	t.Run("lenient skip", func(t *testing.T) {
+		config := DefaultConfig()
+		config.LenientAttributes = true
+		var warnings []string
+		config.OnWarning = func(pos Position, message string) {
+			warnings = append(warnings, message)
+		}
+
+		for _, input := range []string{`<div ="x" class="c">`, `<div = "x" class="c">`, `<div class="c" = >`} {
+			token := NewLexerWithConfig(input, config).NextToken()
+			if token.Type != TokenOpenTag {
+				t.Errorf("%s: expected open tag, got %v %q", input, token.Type, token.Value)
+				continue
+			}
+			if len(token.Attributes) != 1 || token.Attributes["class"] != "c" {
+				t.Errorf("%s: unexpected attributes %v", input, token.Attributes)
+			}
+		}
+		if len(warnings) != 3 {
+			t.Errorf("expected 3 warnings, got %v", warnings)
+		}
+	})
+}
+
+// TestLexerMultilineAttributePosition 测试跨行属性值之后的 token 位置
+func TestLexerMultilineAttributePosition(t *testing.T) {
+	streaming := DefaultConfig()
+	streaming.MaxAttributeValueBytes = 2
+	streaming.AttributeValueSink = func(string, io.Reader) {}
+
+	tests := []struct {
+		name   string
+		input  string
+		config *ParserConfig
+		line   int
+		column int
+	}{
+		{"double quoted", "<a title=\"x\ny\nz\"><b/></a>", nil, 3, 4},
+		{"single quoted with trailing newline", "<a title='x\n'>\n  <b/></a>", nil, 3, 3},
+		{"multibyte runes", "<a title=\"é\n日本\"><b/></a>", nil, 2, 5},
+		{"escaped quote", "<a title=\"x\\\"\ny\"><b/></a>", nil, 2, 4},
+		{"streamed value", "<a title=\"x\ny\nz\"><b/></a>", streaming, 3, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if config == nil {
+				config = DefaultConfig()
+			}
+			lexer := NewLexerWithConfig(tt.input, config)
+			if token := lexer.NextToken(); token.Type != TokenOpenTag {
+				t.Fatalf("expected open tag, got %v %q", token.Type, token.Value)
+			}
+			token := lexer.NextToken()
+			if token.Type != TokenSelfCloseTag {
+				t.Fatalf("expected self-close tag, got %v %q", token.Type, token.Value)
+			}
+			if token.Position.Line != tt.line || token.Position.Column != tt.column {
+				t.Errorf("expected position %d:%d, got %s", tt.line, tt.column, token.Position)
+			}
+			if offset := strings.Index(tt.input, "<b/>") + 1; token.Position.ByteOffset != offset {
+				t.Errorf("expected byte offset %d, got %d", offset, token.Position.ByteOffset)
+			}
+		})
+	}
+}
+
+// TestLexerTrimAttributeValues 测试属性值首尾空白修剪
+func TestLexerTrimAttributeValues(t *testing.T) {
+	input := `<div class=" foo " title='  a b  ' id=x data-sp="&#32;y">`
+
+	t.Run("trimmed under flag", func(t *testing.T) {
+		config := DefaultConfig()
+		config.TrimAttributeValues = true
+		config.DecodeEntities = true
+		token := NewLexerWithConfig(input, config).NextToken()
+		expected := map[string]string{"class": "foo", "title": "a b", "id": "x", "data-sp": " y"}
+		for name, want := range expected {
+			if got := token.Attributes[name]; got != want {
+				t.Errorf("%s: expected %q, got %q", name, want, got)
+			}
+		}
+	})
+
+	t.Run("preserved by default", func(t *testing.T) {
+		token := NewLexer(input).NextToken()
+		if got := token.Attributes["class"]; got != " foo " {
+			t.Errorf("expected padding preserved, got %q", got)
+		}
+	})
+}
+
+// TestLexerBacktickAttributes 测试反引号包裹的属性值
+func TestLexerBacktickAttributes(t *testing.T) {
+	input := "<x attr=`hello world` raw=`a\\b` dq=\"d q\" sq='s q'>"
+
+	t.Run("recognized under flag", func(t *testing.T) {
+		config := DefaultConfig()
+		config.AllowBacktickAttributes = true
+		token := NewLexerWithConfig(input, config).NextToken()
+		if token.Type != TokenOpenTag {
+			t.Fatalf("expected open tag, got %s: %s", token.Type, token.Value)
+		}
+		expected := map[string]string{"attr": "hello world", "raw": `a\b`, "dq": "d q", "sq": "s q"}
+		for name, want := range expected {
+			if got := token.Attributes[name]; got != want {
+				t.Errorf("%s: expected %q, got %q", name, want, got)
+			}
+		}
+	})
+
+	t.Run("unquoted by default", func(t *testing.T) {
+		token := NewLexer("<x attr=`hello>").NextToken()
+		if got := token.Attributes["attr"]; got != "`hello" {
+			t.Errorf("expected back-tick kept in unquoted value, got %q", got)
+		}
+	})
+
+	t.Run("unterminated", func(t *testing.T) {
+		config := DefaultConfig()
+		config.AllowBacktickAttributes = true
+		token := NewLexerWithConfig("<x attr=`hello>", config).NextToken()
+		if token.Type != TokenError {
+			t.Errorf("expected error for unterminated back-tick, got %s", token.Type)
+		}
+	})
+}
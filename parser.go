@@ -1,9 +1,12 @@
 package markit
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
+	"unicode"
 )
 
 // Parser 语法分析器
@@ -13,8 +16,29 @@ type Parser struct {
 	peek      Token
 	processor AttributeProcessor
 	config    *ParserConfig
+	err       error             // 构造阶段产生的错误，由 Parse 返回
+	nsScope   map[string]string // 当前作用域内的命名空间绑定（仅 NamespaceAware 时使用）
+	nodeCount int               // 已创建的节点数量
+	depth     int               // 当前未闭合的元素数量
+	stats     ParseStats        // 解析过程中增量维护的统计信息
+	feed      *feedState        // 增量解析状态（仅在调用 Feed 后使用）
 }
 
+// ParseStats 解析统计信息，用于观察文档复杂度
+type ParseStats struct {
+	Elements int // 元素数量
+	Texts    int // 文本节点数量
+	Comments int // 注释数量
+	MaxDepth int // 达到的最大元素嵌套深度（顶层元素为 1）
+	Tokens   int // 消耗的 token 数量（不含 EOF）
+}
+
+// ErrInputTooLarge 输入超过 MaxInputBytes 限制
+var ErrInputTooLarge = errors.New("input exceeds maximum size")
+
+// ErrUnknownNodeType WalkStrict 遇到无法识别的节点类型
+var ErrUnknownNodeType = errors.New("unknown node type")
+
 // NewParser 创建新的语法分析器（使用默认配置）
 func NewParser(input string) *Parser {
 	return NewParserWithConfig(input, DefaultConfig())
@@ -22,19 +46,27 @@ func NewParser(input string) *Parser {
 
 // NewParserWithConfig 创建带配置的语法分析器
 func NewParserWithConfig(input string, config *ParserConfig) *Parser {
+	// 超过大小限制的输入在词法分析开始前即被拒绝
+	var err error
+	if config.MaxInputBytes > 0 && len(input) > config.MaxInputBytes {
+		err = fmt.Errorf("%w: %d bytes exceeds limit of %d", ErrInputTooLarge, len(input), config.MaxInputBytes)
+		input = ""
+	}
+
 	lexer := NewLexerWithConfig(input, config)
 	p := &Parser{
 		lexer:     lexer,
 		processor: config.AttributeProcessor,
 		config:    config,
+		err:       err,
 	}
 
 	// 读取前两个 token，跳过注释
 	p.nextToken()
 	p.nextToken()
 
-	// 如果配置要求跳过注释，则跳过它们
-	if p.config.SkipComments {
+	// 如果配置要求跳过注释，则跳过它们（KeepLeadingComment 时保留文档开头的注释）
+	if p.config.SkipComments && !p.config.KeepLeadingComment {
 		for p.current.Type == TokenComment {
 			p.nextToken()
 		}
@@ -43,6 +75,37 @@ func NewParserWithConfig(input string, config *ParserConfig) *Parser {
 	return p
 }
 
+// NewParserFromReader 从 Reader 读取输入并创建语法分析器
+// 设置了 MaxInputBytes 时最多读取限制字节数，超出部分会导致返回 ErrInputTooLarge
+func NewParserFromReader(r io.Reader, config *ParserConfig) (*Parser, error) {
+	if r == nil {
+		return nil, fmt.Errorf("reader is nil")
+	}
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	if config.MaxInputBytes > 0 {
+		// 多读一个字节用于判断是否被截断
+		r = io.LimitReader(r, int64(config.MaxInputBytes)+1)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if config.MaxInputBytes > 0 && len(data) > config.MaxInputBytes {
+		return nil, fmt.Errorf("%w: reader exceeds limit of %d bytes", ErrInputTooLarge, config.MaxInputBytes)
+	}
+
+	return NewParserWithConfig(string(data), config), nil
+}
+
+// Stats 返回解析统计信息，应在 Parse 之后调用
+func (p *Parser) Stats() ParseStats {
+	return p.stats
+}
+
 // SetAttributeProcessor 设置属性处理器
 func (p *Parser) SetAttributeProcessor(processor AttributeProcessor) {
 	p.processor = processor
@@ -64,30 +127,51 @@ func (p *Parser) SetConfig(config *ParserConfig) {
 
 // Parse 解析输入并返回 AST
 func (p *Parser) Parse() (*Document, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
 	doc := &Document{
 		Children: []Node{},
 		Pos:      p.current.Position,
 	}
+	if err := p.parseInto(doc, true); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// parseInto 解析到 EOF 为止的所有顶层节点并追加到 doc，leading 表示当前位于文档开头
+func (p *Parser) parseInto(doc *Document, leading bool) error {
+	// 跳过注释时保留文档开头的第一个注释（如许可证声明）
+	if leading && p.config.SkipComments && p.config.KeepLeadingComment && p.current.Type == TokenComment {
+		node, err := p.parseComment()
+		if err != nil {
+			return err
+		}
+		doc.Children = append(doc.Children, node)
+	}
 
 	for p.current.Type != TokenEOF {
 		node, err := p.parseNode()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if node != nil {
 			doc.Children = append(doc.Children, node)
+			setParent(node, doc)
 		}
 	}
 
-	return doc, nil
+	return nil
 }
 
 // parseNode 解析一个节点
 func (p *Parser) parseNode() (Node, error) {
-	// 如果配置要求跳过注释，则跳过注释token
-	if p.config.SkipComments && p.current.Type == TokenComment {
+	// 如果配置要求跳过注释，则循环跳过连续的注释token，避免逐个递归
+	for p.config.SkipComments && p.current.Type == TokenComment {
 		p.nextToken()
-		return p.parseNode() // 递归解析下一个节点
 	}
 
 	switch p.current.Type {
@@ -105,6 +189,12 @@ func (p *Parser) parseNode() (Node, error) {
 		return p.parseCDATA()
 	case TokenComment:
 		return p.parseComment()
+	case TokenCloseTag:
+		// 元素内的结束标签由 parseElement 处理，到达这里说明没有匹配的开始标签
+		return nil, &ParseError{
+			Position: p.current.Position,
+			Message:  fmt.Sprintf("unexpected closing tag </%s> with no matching open", p.current.Value),
+		}
 	case TokenError:
 		return nil, &ParseError{
 			Position: p.current.Position,
@@ -129,16 +219,22 @@ func (p *Parser) parseText() (Node, error) {
 		}
 	}
 
+	if err := p.trackNode(); err != nil {
+		return nil, err
+	}
+
 	text := &Text{
 		Content: p.current.Value,
 		Pos:     p.current.Position,
 	}
+	p.stats.Texts++
 
 	p.nextToken()
 	return text, nil
 }
 
 // parseElement 解析元素节点
+// 使用显式栈保存未闭合的元素，嵌套深度不会增加 Go 调用栈
 func (p *Parser) parseElement() (Node, error) {
 	if p.current.Type != TokenOpenTag {
 		return nil, &ParseError{
@@ -147,13 +243,123 @@ func (p *Parser) parseElement() (Node, error) {
 		}
 	}
 
+	outerScope, outerDepth := p.nsScope, p.depth
+	defer func() { p.nsScope, p.depth = outerScope, outerDepth }()
+
+	root, complete, err := p.openElement()
+	if err != nil {
+		return nil, err
+	}
+	if complete {
+		return root, nil
+	}
+
+	stack := []*Element{root}
+	p.nsScope = root.Namespaces
+	p.depth += nestingLevel(root)
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+
+		switch {
+		case p.current.Type == TokenCloseTag || p.current.Type == TokenEOF:
+			if err := p.closeElement(top); err != nil {
+				return nil, err
+			}
+			stack = stack[:len(stack)-1]
+			p.depth -= nestingLevel(top)
+			if len(stack) > 0 {
+				p.nsScope = stack[len(stack)-1].Namespaces
+				// 片段在解析期间以空标签名的元素占位，闭合后替换为 Fragment（此时必为父元素的最后一个子节点）
+				if parent := stack[len(stack)-1]; top.TagName == "" {
+					parent.Children[len(parent.Children)-1] = newFragment(top)
+				}
+			} else if top.TagName == "" {
+				return newFragment(top), nil
+			}
+		case p.current.Type == TokenOpenTag:
+			child, complete, err := p.openElement()
+			if err != nil {
+				return nil, err
+			}
+			top.Children = append(top.Children, child)
+			child.Parent = top
+			if !complete {
+				stack = append(stack, child)
+				p.nsScope = child.Namespaces
+				p.depth += nestingLevel(child)
+			}
+		case p.current.Type == TokenComment && p.config.SkipComments:
+			p.nextToken()
+		default:
+			child, err := p.parseNode()
+			if err != nil {
+				return nil, err
+			}
+			if child != nil {
+				top.Children = append(top.Children, child)
+				setParent(child, top)
+			}
+		}
+	}
+
+	return root, nil
+}
+
+// coerceBooleanAttributes 使用属性处理器规范布尔属性，处理结果为 true 的属性按无值的布尔属性保存（如 <x my-flag="">）
+func (p *Parser) coerceBooleanAttributes(element *Element) error {
+	if p.processor == nil {
+		return nil
+	}
+	for _, name := range element.AttributeOrder {
+		if !p.processor.IsBooleanAttribute(name) {
+			continue
+		}
+		_, value, err := p.processor.ProcessAttribute(name, element.Attributes[name])
+		if err != nil {
+			pos := element.Pos
+			if attrPos, ok := element.AttributePos[name]; ok {
+				pos = attrPos
+			}
+			return &ParseError{Position: pos, Message: err.Error()}
+		}
+		if value == true {
+			element.Attributes[name] = ""
+			delete(element.EmptyAttributes, name)
+		}
+	}
+	return nil
+}
+
+// newFragment 将占位元素转换为片段节点，子元素的父节点改为该片段
+func newFragment(placeholder *Element) *Fragment {
+	fragment := &Fragment{Children: placeholder.Children, Pos: placeholder.Pos}
+	adoptChildren(fragment, fragment.Children)
+	return fragment
+}
+
+// openElement 根据当前开始标签创建元素
+// 返回的 complete 表示元素已完整（void element），无需等待结束标签
+func (p *Parser) openElement() (*Element, bool, error) {
+	if err := p.trackNode(); err != nil {
+		return nil, false, err
+	}
+
 	element := &Element{
-		TagName:    p.current.Value,
-		Attributes: p.current.Attributes,
-		Children:   []Node{},
-		SelfClose:  false,
-		Pos:        p.current.Position,
+		TagName:           p.current.Value,
+		Attributes:        p.current.Attributes,
+		Children:          []Node{},
+		SelfClose:         false,
+		Pos:               p.current.Position,
+		AttributeOrder:    p.current.AttributeOrder,
+		EmptyAttributes:   p.current.EmptyAttributes,
+		DecodedAttributes: p.current.DecodedAttributes,
+		AttributePos:      p.current.AttributePositions,
 	}
+	if err := p.coerceBooleanAttributes(element); err != nil {
+		return nil, false, err
+	}
+	p.bindNamespaces(element)
+	p.countElement(element)
 
 	tagName := p.current.Value
 	p.nextToken()
@@ -162,37 +368,61 @@ func (p *Parser) parseElement() (Node, error) {
 	if p.config != nil && p.config.IsVoidElement(tagName) {
 		// void element 不需要结束标签，直接返回自闭合元素
 		element.SelfClose = true
-		return element, nil
+		if err := p.skipVoidElementClose(tagName); err != nil {
+			return nil, false, err
+		}
+		p.completeElement(element)
+		return element, true, nil
 	}
 
-	// 解析子节点
-	for p.current.Type != TokenCloseTag && p.current.Type != TokenEOF {
-		child, err := p.parseNode()
-		if err != nil {
-			return nil, err
-		}
-		if child != nil {
-			element.Children = append(element.Children, child)
+	return element, false, nil
+}
+
+// skipVoidElementClose 处理紧跟在 void element 之后的同名结束标签
+// 开启 ErrorOnVoidElementClose 时报错，开启 AllowVoidElementClose 时跳过该结束标签，
+// 否则保留给外层元素，作为不匹配的结束标签报错
+func (p *Parser) skipVoidElementClose(tagName string) error {
+	if p.current.Type != TokenCloseTag || !p.config.namesEqual(p.current.Value, tagName) {
+		return nil
+	}
+	if p.config.ErrorOnVoidElementClose {
+		return &ParseError{
+			Position: p.current.Position,
+			Message:  fmt.Sprintf("void element <%s> must not have a closing tag", tagName),
 		}
 	}
+	if p.config.AllowVoidElementClose {
+		p.nextToken()
+	}
+	return nil
+}
+
+// closeElement 检查当前结束标签是否与元素匹配并完成该元素
+func (p *Parser) closeElement(element *Element) error {
+	tagName := element.TagName
 
 	// 检查结束标签
 	if p.current.Type != TokenCloseTag {
-		return nil, &ParseError{
+		return &ParseError{
 			Position: p.current.Position,
 			Message:  fmt.Sprintf("expected close tag for <%s>, got %s", tagName, p.current.Type),
 		}
 	}
 
-	if p.current.Value != tagName {
-		return nil, &ParseError{
+	anonymous := p.current.Value == "" && p.config.AllowAnonymousClose
+	if !p.config.namesEqual(p.current.Value, tagName) && !anonymous {
+		return &ParseError{
 			Position: p.current.Position,
 			Message:  fmt.Sprintf("mismatched tags: expected </%s>, got </%s>", tagName, p.current.Value),
 		}
 	}
 
+	if p.config.TrackCloseTagPositions {
+		element.CloseTagPos = p.current.Position
+	}
 	p.nextToken()
-	return element, nil
+	p.completeElement(element)
+	return nil
 }
 
 // parseSelfCloseElement 解析自闭合元素
@@ -204,18 +434,109 @@ func (p *Parser) parseSelfCloseElement() (Node, error) {
 		}
 	}
 
+	if err := p.trackNode(); err != nil {
+		return nil, err
+	}
+
 	element := &Element{
-		TagName:    p.current.Value,
-		Attributes: p.current.Attributes,
-		Children:   []Node{},
-		SelfClose:  true,
-		Pos:        p.current.Position,
+		TagName:           p.current.Value,
+		Attributes:        p.current.Attributes,
+		Children:          []Node{},
+		SelfClose:         true,
+		Pos:               p.current.Position,
+		AttributeOrder:    p.current.AttributeOrder,
+		EmptyAttributes:   p.current.EmptyAttributes,
+		DecodedAttributes: p.current.DecodedAttributes,
+		AttributePos:      p.current.AttributePositions,
+	}
+	if err := p.coerceBooleanAttributes(element); err != nil {
+		return nil, err
 	}
+	p.bindNamespaces(element)
+	p.countElement(element)
 
 	p.nextToken()
+	p.completeElement(element)
 	return element, nil
 }
 
+// bindNamespaces 记录元素作用域内的命名空间绑定
+// 没有新增声明时与父作用域共享同一个映射
+func (p *Parser) bindNamespaces(element *Element) {
+	if p.config == nil || !p.config.NamespaceAware {
+		return
+	}
+
+	scope := p.nsScope
+	copied := false
+	for key, value := range element.Attributes {
+		prefix, ok := namespaceDeclPrefix(key)
+		if !ok {
+			continue
+		}
+
+		if !copied {
+			scope = make(map[string]string, len(p.nsScope)+1)
+			for k, v := range p.nsScope {
+				scope[k] = v
+			}
+			copied = true
+		}
+		scope[prefix] = value
+	}
+	element.Namespaces = scope
+}
+
+// namespaceDeclPrefix 返回命名空间声明属性绑定的前缀（xmlns 为默认命名空间 ""），非声明属性返回 false
+func namespaceDeclPrefix(key string) (string, bool) {
+	switch {
+	case key == "xmlns":
+		return "", true
+	case strings.HasPrefix(key, "xmlns:"):
+		return key[len("xmlns:"):], true
+	default:
+		return "", false
+	}
+}
+
+// trackNode 记录新建节点并检查 MaxNodes 限制
+func (p *Parser) trackNode() error {
+	p.nodeCount++
+	if p.config != nil && p.config.MaxNodes > 0 && p.nodeCount > p.config.MaxNodes {
+		return &ParseError{
+			Position: p.current.Position,
+			Message:  fmt.Sprintf("node count exceeds limit of %d", p.config.MaxNodes),
+		}
+	}
+	return nil
+}
+
+// countElement 统计新建元素并更新最大深度，片段占位元素不计入统计
+func (p *Parser) countElement(element *Element) {
+	if element.TagName == "" {
+		return
+	}
+	p.stats.Elements++
+	if depth := p.depth + 1; depth > p.stats.MaxDepth {
+		p.stats.MaxDepth = depth
+	}
+}
+
+// nestingLevel 返回元素对嵌套深度的贡献，片段占位元素是透明的，不增加深度
+func nestingLevel(element *Element) int {
+	if element.TagName == "" {
+		return 0
+	}
+	return 1
+}
+
+// completeElement 在元素解析完成后调用 OnElement 回调（片段占位元素除外）
+func (p *Parser) completeElement(element *Element) {
+	if p.config != nil && p.config.OnElement != nil && element.TagName != "" {
+		p.config.OnElement(element)
+	}
+}
+
 // parseProcessingInstruction 解析处理指令
 func (p *Parser) parseProcessingInstruction() (Node, error) {
 	if p.current.Type != TokenProcessingInstruction {
@@ -225,16 +546,73 @@ func (p *Parser) parseProcessingInstruction() (Node, error) {
 		}
 	}
 
+	if err := p.trackNode(); err != nil {
+		return nil, err
+	}
+
+	target, content := splitProcessingInstruction(p.current.Value)
 	pi := &ProcessingInstruction{
-		Target:  p.current.Value,
-		Content: p.current.Value,
+		Target:  target,
+		Content: content,
 		Pos:     p.current.Position,
 	}
 
+	if p.config != nil && p.config.ParsePseudoAttributes {
+		if attrs, ok := parsePseudoAttributes(pi.Content); ok {
+			pi.PseudoAttributes = attrs
+		}
+	}
+
 	p.nextToken()
 	return pi, nil
 }
 
+// splitProcessingInstruction 将处理指令拆分为目标和内容，会去除可能存在的 <? 和 ?> 定界符
+func splitProcessingInstruction(value string) (string, string) {
+	value = strings.TrimPrefix(value, "<?")
+	value = strings.TrimSuffix(value, "?>")
+	value = strings.TrimSpace(value)
+
+	if i := strings.IndexFunc(value, unicode.IsSpace); i >= 0 {
+		return value[:i], strings.TrimSpace(value[i:])
+	}
+	return value, ""
+}
+
+// parsePseudoAttributes 解析处理指令中形如 name="value" 的伪属性
+// 内容不符合伪属性语法时返回 false
+func parsePseudoAttributes(content string) (map[string]string, bool) {
+	if content == "" {
+		return nil, false
+	}
+
+	l := NewLexerWithConfig(content, DefaultConfig())
+	attrs := make(map[string]string)
+	l.skipWhitespace()
+	for l.current != 0 {
+		name := l.readIdentifier()
+		if name == "" {
+			return nil, false
+		}
+		l.skipWhitespace()
+		if l.current != '=' {
+			return nil, false
+		}
+		l.readChar() // 跳过 '='
+		l.skipWhitespace()
+		if l.current != '"' && l.current != '\'' {
+			return nil, false
+		}
+		value, err := l.readAttributeValue()
+		if err != nil {
+			return nil, false
+		}
+		attrs[name] = value
+		l.skipWhitespace()
+	}
+	return attrs, true
+}
+
 // parseDoctype 解析DOCTYPE声明
 func (p *Parser) parseDoctype() (Node, error) {
 	if p.current.Type != TokenDoctype {
@@ -244,10 +622,17 @@ func (p *Parser) parseDoctype() (Node, error) {
 		}
 	}
 
+	if err := p.trackNode(); err != nil {
+		return nil, err
+	}
+
+	content, subset := splitDoctype(p.current.Value)
 	doctype := &Doctype{
-		Content: p.current.Value,
-		Pos:     p.current.Position,
+		Content:        content,
+		InternalSubset: subset,
+		Pos:            p.current.Position,
 	}
+	doctype.Entities, doctype.Elements = parseInternalSubset(subset)
 
 	p.nextToken()
 	return doctype, nil
@@ -262,13 +647,26 @@ func (p *Parser) parseCDATA() (Node, error) {
 		}
 	}
 
-	cdata := &CDATA{
-		Content: p.current.Value,
-		Pos:     p.current.Position,
+	if err := p.trackNode(); err != nil {
+		return nil, err
+	}
+
+	var node Node
+	if p.config != nil && p.config.CDATAAsText {
+		node = &Text{
+			Content: p.current.Value,
+			Pos:     p.current.Position,
+		}
+		p.stats.Texts++
+	} else {
+		node = &CDATA{
+			Content: p.current.Value,
+			Pos:     p.current.Position,
+		}
 	}
 
 	p.nextToken()
-	return cdata, nil
+	return node, nil
 }
 
 // parseComment 解析注释节点
@@ -280,10 +678,15 @@ func (p *Parser) parseComment() (Node, error) {
 		}
 	}
 
+	if err := p.trackNode(); err != nil {
+		return nil, err
+	}
+
 	comment := &Comment{
 		Content: p.current.Value,
 		Pos:     p.current.Position,
 	}
+	p.stats.Comments++
 
 	p.nextToken()
 	return comment, nil
@@ -293,6 +696,9 @@ func (p *Parser) parseComment() (Node, error) {
 func (p *Parser) nextToken() {
 	p.current = p.peek
 	p.peek = p.lexer.NextToken()
+	if p.peek.Type != TokenEOF {
+		p.stats.Tokens++
+	}
 
 	// 不在这里跳过注释，让parseNode处理
 }
@@ -319,14 +725,26 @@ type Visitor interface {
 }
 
 // Walk 遍历 AST
+// 叶子节点（Text、Comment 等）作为起点时只调用对应的访问方法，
+// 无法识别的节点类型（如自定义 Node 实现）会被静默忽略，需要报错时使用 WalkStrict
 func Walk(node Node, visitor Visitor) error {
+	return walk(node, visitor, false)
+}
+
+// WalkStrict 与 Walk 相同，但遇到无法识别的节点类型时返回 ErrUnknownNodeType
+func WalkStrict(node Node, visitor Visitor) error {
+	return walk(node, visitor, true)
+}
+
+// walk Walk 和 WalkStrict 的递归实现
+func walk(node Node, visitor Visitor, strict bool) error {
 	switch n := node.(type) {
 	case *Document:
 		if err := visitor.VisitDocument(n); err != nil {
 			return err
 		}
 		for _, child := range n.Children {
-			if err := Walk(child, visitor); err != nil {
+			if err := walk(child, visitor, strict); err != nil {
 				return err
 			}
 		}
@@ -335,7 +753,14 @@ func Walk(node Node, visitor Visitor) error {
 			return err
 		}
 		for _, child := range n.Children {
-			if err := Walk(child, visitor); err != nil {
+			if err := walk(child, visitor, strict); err != nil {
+				return err
+			}
+		}
+	case *Fragment:
+		// 片段没有对应的访问方法，直接遍历其子节点
+		for _, child := range n.Children {
+			if err := walk(child, visitor, strict); err != nil {
 				return err
 			}
 		}
@@ -349,6 +774,53 @@ func Walk(node Node, visitor Visitor) error {
 		return visitor.VisitCDATA(n)
 	case *Comment:
 		return visitor.VisitComment(n)
+	default:
+		if strict {
+			return fmt.Errorf("%w: %T", ErrUnknownNodeType, node)
+		}
+	}
+	return nil
+}
+
+// WalkDepth 遍历 AST，最多深入到起始节点以下 maxDepth 层
+// maxDepth 为 0 时只访问起始节点，为 1 时访问起始节点及其直接子节点
+func WalkDepth(node Node, maxDepth int, visitor Visitor) error {
+	return walkDepth(node, 0, maxDepth, visitor)
+}
+
+// walkDepth WalkDepth 的递归实现
+func walkDepth(node Node, depth, maxDepth int, visitor Visitor) error {
+	var children []Node
+	switch n := node.(type) {
+	case *Document:
+		if err := visitor.VisitDocument(n); err != nil {
+			return err
+		}
+		children = n.Children
+	case *Element:
+		if err := visitor.VisitElement(n); err != nil {
+			return err
+		}
+		children = n.Children
+	case *Fragment:
+		// 片段是透明的，子节点与片段处于同一深度
+		for _, child := range n.Children {
+			if err := walkDepth(child, depth, maxDepth, visitor); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return Walk(node, visitor)
+	}
+
+	if depth >= maxDepth {
+		return nil
+	}
+	for _, child := range children {
+		if err := walkDepth(child, depth+1, maxDepth, visitor); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -359,9 +831,39 @@ func PrettyPrint(node Node) string {
 	return debugRenderer.RenderDebug(node)
 }
 
+// countNodes 返回节点及其所有后代节点的数量
+func countNodes(node Node) int {
+	count := 1
+	var children []Node
+	switch n := node.(type) {
+	case *Document:
+		children = n.Children
+	case *Element:
+		children = n.Children
+	case *Fragment:
+		children = n.Children
+	}
+	for _, child := range children {
+		count += countNodes(child)
+	}
+	return count
+}
+
+// PrettyPrintN 与 PrettyPrint 相同，但最多输出 maxNodes 个节点（含 Document 节点），
+// 超出部分以 "... (N more nodes)" 标记结尾，适合在日志中输出大型文档
+func PrettyPrintN(node Node, maxNodes int) string {
+	debugRenderer := NewDebugRenderer()
+	debugRenderer.maxNodes = maxNodes
+	return debugRenderer.RenderDebug(node)
+}
+
 // DebugRenderer 调试渲染器，专门用于AST结构展示
 type DebugRenderer struct {
 	*Renderer
+
+	maxNodes int // 最多输出的节点数（0 表示不限制）
+	rendered int // 已输出的节点数
+	skipped  int // 超出限制而省略的节点数
 }
 
 // NewDebugRenderer 创建调试渲染器
@@ -381,7 +883,11 @@ func NewDebugRenderer() *DebugRenderer {
 // RenderDebug 渲染调试信息
 func (dr *DebugRenderer) RenderDebug(node Node) string {
 	var sb strings.Builder
+	dr.rendered, dr.skipped = 0, 0
 	dr.renderDebugNode(node, &sb, 0)
+	if dr.skipped > 0 {
+		sb.WriteString(fmt.Sprintf("... (%d more nodes)\n", dr.skipped))
+	}
 	return sb.String()
 }
 
@@ -390,6 +896,11 @@ func (dr *DebugRenderer) renderDebugNode(node Node, sb *strings.Builder, depth i
 	if node == nil {
 		return
 	}
+	if dr.maxNodes > 0 && dr.rendered >= dr.maxNodes {
+		dr.skipped += countNodes(node)
+		return
+	}
+	dr.rendered++
 
 	indentStr := strings.Repeat(dr.options.Indent, depth)
 
@@ -437,10 +948,16 @@ func (dr *DebugRenderer) renderDebugNode(node Node, sb *strings.Builder, depth i
 	case *ProcessingInstruction:
 		sb.WriteString(fmt.Sprintf("%sPI: %q\n", indentStr, n.Content))
 	case *Doctype:
-		sb.WriteString(fmt.Sprintf("%sDoctype: %q\n", indentStr, n.Content))
+		sb.WriteString(fmt.Sprintf("%sDoctype: %q\n", indentStr, n.declaration()))
 	case *CDATA:
 		sb.WriteString(fmt.Sprintf("%sCDATA: %q\n", indentStr, n.Content))
 	case *Comment:
 		sb.WriteString(fmt.Sprintf("%sComment: %q\n", indentStr, n.Content))
+	case *Fragment:
+		sb.WriteString(fmt.Sprintf("%s<>\n", indentStr))
+		for _, child := range n.Children {
+			dr.renderDebugNode(child, sb, depth+1)
+		}
+		sb.WriteString(fmt.Sprintf("%s</>\n", indentStr))
 	}
 }
@@ -1,7 +1,9 @@
 package markit
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
 )
@@ -253,7 +255,7 @@ func TestParserErrorHandling(t *testing.T) {
 			name:        "empty tag name",
 			input:       "<>content</>",
 			expectError: true,
-			errorMsg:    "empty tag",
+			errorMsg:    "empty tag <>",
 		},
 		{
 			name:        "nested mismatched tags",
@@ -678,6 +680,34 @@ func TestParserPrettyPrint(t *testing.T) {
 	}
 }
 
+// TestPrettyPrintN 测试美化输出的节点数量限制
+func TestPrettyPrintN(t *testing.T) {
+	input := "<root>" + strings.Repeat("<item />", 50) + "</root>"
+	doc, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	// Document + root + 8 个 item，剩余 42 个 item 被省略
+	output := PrettyPrintN(doc, 10)
+	if got := strings.Count(output, "<item"); got != 8 {
+		t.Errorf("expected 8 rendered items, got %d:\n%s", got, output)
+	}
+	if !strings.HasSuffix(output, "... (42 more nodes)\n") {
+		t.Errorf("expected cutoff marker, got:\n%s", output)
+	}
+	if !strings.Contains(output, "</root>") {
+		t.Errorf("expected open elements to be closed, got:\n%s", output)
+	}
+
+	if full := PrettyPrintN(doc, 0); full != PrettyPrint(doc) {
+		t.Error("maxNodes <= 0 should render the whole tree")
+	}
+	if full := PrettyPrintN(doc, 52); strings.Contains(full, "more nodes") {
+		t.Errorf("no marker expected when the limit covers the tree, got:\n%s", full)
+	}
+}
+
 // TestParserSkipComments 测试跳过注释功能
 func TestParserSkipComments(t *testing.T) {
 	tests := []struct {
@@ -1150,3 +1180,1046 @@ func TestPrettyPrintAllNodeTypes(t *testing.T) {
 
 	t.Logf("PrettyPrint output:\n%s", output)
 }
+
+// TestTrimCommentWhitespaceConfiguration 测试注释空白修剪独立配置
+func TestTrimCommentWhitespaceConfiguration(t *testing.T) {
+	banner := "\n  +------+\n  | logo |\n  +------+\n"
+	input := "<root>  text  <!--" + banner + "--></root>"
+
+	boolPtr := func(b bool) *bool { return &b }
+
+	tests := []struct {
+		name            string
+		trimWhitespace  bool
+		trimComment     *bool
+		expectedText    string
+		expectedComment string
+	}{
+		{
+			name:            "text trimmed, comment preserved",
+			trimWhitespace:  true,
+			trimComment:     boolPtr(false),
+			expectedText:    "text",
+			expectedComment: banner,
+		},
+		{
+			name:            "text preserved, comment trimmed",
+			trimWhitespace:  false,
+			trimComment:     boolPtr(true),
+			expectedText:    "  text  ",
+			expectedComment: strings.TrimSpace(banner),
+		},
+		{
+			name:            "nil follows TrimWhitespace",
+			trimWhitespace:  true,
+			trimComment:     nil,
+			expectedText:    "text",
+			expectedComment: strings.TrimSpace(banner),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.TrimWhitespace = tt.trimWhitespace
+			config.TrimCommentWhitespace = tt.trimComment
+
+			doc, err := NewParserWithConfig(input, config).Parse()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+
+			root := doc.Children[0].(*Element)
+			if len(root.Children) != 2 {
+				t.Fatalf("expected 2 children, got %d", len(root.Children))
+			}
+			if text := root.Children[0].(*Text); text.Content != tt.expectedText {
+				t.Errorf("expected text %q, got %q", tt.expectedText, text.Content)
+			}
+			if comment := root.Children[1].(*Comment); comment.Content != tt.expectedComment {
+				t.Errorf("expected comment %q, got %q", tt.expectedComment, comment.Content)
+			}
+		})
+	}
+}
+
+// TestOnElementCallback 测试元素解析完成回调
+func TestOnElementCallback(t *testing.T) {
+	t.Run("post-order callback", func(t *testing.T) {
+		var order []string
+		config := DefaultConfig()
+		config.AddVoidElement("br")
+		config.OnElement = func(e *Element) {
+			order = append(order, e.TagName)
+		}
+
+		input := `<a><b><c/><d>text</d></b><br><e></e></a><f/>`
+		doc, err := NewParserWithConfig(input, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+
+		// 计算文档的后序遍历
+		var expected []string
+		var postOrder func(Node)
+		postOrder = func(n Node) {
+			if e, ok := n.(*Element); ok {
+				for _, child := range e.Children {
+					postOrder(child)
+				}
+				expected = append(expected, e.TagName)
+			}
+		}
+		for _, child := range doc.Children {
+			postOrder(child)
+		}
+
+		if strings.Join(order, ",") != "c,d,b,br,e,a,f" {
+			t.Errorf("unexpected callback order: %v", order)
+		}
+		if strings.Join(order, ",") != strings.Join(expected, ",") {
+			t.Errorf("callback order %v does not match post-order %v", order, expected)
+		}
+	})
+
+	t.Run("element subtree is complete", func(t *testing.T) {
+		config := DefaultConfig()
+		config.OnElement = func(e *Element) {
+			if e.TagName == "list" && len(e.Children) != 3 {
+				t.Errorf("expected 3 children at callback time, got %d", len(e.Children))
+			}
+		}
+		if _, err := NewParserWithConfig("<list><i/><i/><i/></list>", config).Parse(); err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+	})
+
+	t.Run("nil callback", func(t *testing.T) {
+		config := DefaultConfig()
+		config.OnElement = nil
+		if _, err := NewParserWithConfig("<a><b/></a>", config).Parse(); err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+	})
+}
+
+// TestProcessingInstructionPseudoAttributes 测试处理指令伪属性解析与往返渲染
+func TestProcessingInstructionPseudoAttributes(t *testing.T) {
+	parsePI := func(t *testing.T, config *ParserConfig, input string) *ProcessingInstruction {
+		t.Helper()
+		doc, err := NewParserWithConfig(input, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		pi, ok := doc.Children[0].(*ProcessingInstruction)
+		if !ok {
+			t.Fatalf("expected ProcessingInstruction, got %T", doc.Children[0])
+		}
+		return pi
+	}
+
+	config := DefaultConfig()
+	config.ParsePseudoAttributes = true
+	renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true, IncludeDeclaration: true})
+
+	t.Run("xml-stylesheet round trip", func(t *testing.T) {
+		input := `<?xml-stylesheet type="text/xsl" href='s.xsl'?><root/>`
+		doc, err := NewParserWithConfig(input, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		pi := doc.Children[0].(*ProcessingInstruction)
+		if pi.Target != "xml-stylesheet" {
+			t.Errorf("expected target xml-stylesheet, got %q", pi.Target)
+		}
+		if pi.PseudoAttributes["type"] != "text/xsl" || pi.PseudoAttributes["href"] != "s.xsl" {
+			t.Fatalf("unexpected pseudo attributes: %v", pi.PseudoAttributes)
+		}
+
+		output, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		expected := `<?xml-stylesheet href="s.xsl" type="text/xsl"?><root />`
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+
+		again := parsePI(t, config, output)
+		if again.Target != pi.Target || len(again.PseudoAttributes) != 2 ||
+			again.PseudoAttributes["type"] != "text/xsl" || again.PseudoAttributes["href"] != "s.xsl" {
+			t.Errorf("round trip mismatch: %+v", again)
+		}
+	})
+
+	t.Run("non attribute content falls back to raw", func(t *testing.T) {
+		pi := parsePI(t, config, "<?php echo 'hi'; ?>")
+		if pi.Target != "php" || pi.Content != "echo 'hi';" {
+			t.Errorf("unexpected split: %q / %q", pi.Target, pi.Content)
+		}
+		if pi.PseudoAttributes != nil {
+			t.Errorf("expected nil pseudo attributes, got %v", pi.PseudoAttributes)
+		}
+
+		output, _ := renderer.RenderToString(&Document{Children: []Node{pi}})
+		if output != "<?php echo 'hi';?>" {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		input := `<?xml-stylesheet type="text/xsl"?>`
+		pi := parsePI(t, DefaultConfig(), input)
+		if pi.PseudoAttributes != nil || pi.Target != "xml-stylesheet" || pi.Content != `type="text/xsl"` {
+			t.Errorf("expected unparsed processing instruction, got %+v", pi)
+		}
+		if output, _ := renderer.RenderToString(&Document{Children: []Node{pi}}); output != input {
+			t.Errorf("expected raw content round trip, got %q", output)
+		}
+	})
+
+	t.Run("unterminated", func(t *testing.T) {
+		if _, err := NewParserWithConfig(`<?xml version="1.0"`, config).Parse(); err == nil ||
+			!strings.Contains(err.Error(), "unterminated processing instruction") {
+			t.Errorf("expected unterminated processing instruction error, got %v", err)
+		}
+	})
+}
+
+// TestMaxInputBytes 测试输入大小限制
+func TestMaxInputBytes(t *testing.T) {
+	t.Run("over-limit string rejected", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxInputBytes = 10
+
+		_, err := NewParserWithConfig("<root>0123456789</root>", config).Parse()
+		if !errors.Is(err, ErrInputTooLarge) {
+			t.Fatalf("expected ErrInputTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxInputBytes = len("<root/>")
+
+		doc, err := NewParserWithConfig("<root/>", config).Parse()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(doc.Children) != 1 {
+			t.Errorf("expected 1 child, got %d", len(doc.Children))
+		}
+	})
+
+	t.Run("streaming reader exceeding cap", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxInputBytes = 1024
+
+		// 无限输入：超出限制后应停止读取
+		reader := io.MultiReader(strings.NewReader("<root>"), infiniteReader{})
+		_, err := NewParserFromReader(reader, config)
+		if !errors.Is(err, ErrInputTooLarge) {
+			t.Fatalf("expected ErrInputTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("reader within cap", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxInputBytes = 1024
+
+		parser, err := NewParserFromReader(strings.NewReader("<root><p>hi</p></root>"), config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		doc, err := parser.Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		if doc.Children[0].(*Element).TagName != "root" {
+			t.Errorf("unexpected document: %s", PrettyPrint(doc))
+		}
+	})
+
+	t.Run("nil reader", func(t *testing.T) {
+		if _, err := NewParserFromReader(nil, nil); err == nil {
+			t.Error("expected error for nil reader")
+		}
+	})
+}
+
+// infiniteReader 无限输出 'x' 的 Reader
+type infiniteReader struct{}
+
+func (infiniteReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+// TestDeeplyNestedParsing 测试深度嵌套文档的解析
+func TestDeeplyNestedParsing(t *testing.T) {
+	const depth = 5000
+	input := strings.Repeat("<n>", depth) + "leaf" + strings.Repeat("</n>", depth)
+
+	t.Run("well-formed deep document", func(t *testing.T) {
+		doc, err := NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+
+		level := 0
+		node := doc.Children[0]
+		for {
+			elem, ok := node.(*Element)
+			if !ok {
+				break
+			}
+			level++
+			if len(elem.Children) != 1 {
+				t.Fatalf("expected 1 child at level %d, got %d", level, len(elem.Children))
+			}
+			node = elem.Children[0]
+		}
+		if level != depth {
+			t.Errorf("expected depth %d, got %d", depth, level)
+		}
+		if text, ok := node.(*Text); !ok || text.Content != "leaf" {
+			t.Errorf("expected leaf text, got %v", node)
+		}
+	})
+
+	t.Run("error messages", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{strings.Repeat("<n>", depth), "expected close tag for <n>, got EOF"},
+			{"<a><b></a></b>", "mismatched tags: expected </b>, got </a>"},
+			{"<a><b></b>", "expected close tag for <a>, got EOF"},
+		}
+		for _, tt := range tests {
+			_, err := NewParser(tt.input).Parse()
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error %q, got %v", tt.expected, err)
+			}
+		}
+	})
+
+	t.Run("skipped comment before close tag", func(t *testing.T) {
+		config := DefaultConfig()
+		config.SkipComments = true
+
+		doc, err := NewParserWithConfig("<a><b/><!-- c --></a>", config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		if len(doc.Children[0].(*Element).Children) != 1 {
+			t.Errorf("expected comment to be skipped: %s", PrettyPrint(doc))
+		}
+	})
+}
+
+// TestStrayCloseTag 测试文档层级多余的结束标签
+func TestStrayCloseTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		message  string
+		position Position
+	}{
+		{"lone close tag", "</div>", "unexpected closing tag </div> with no matching open", Position{Line: 1, Column: 1}},
+		{"after complete element", "<a></a></b>", "unexpected closing tag </b> with no matching open", Position{Line: 1, Column: 8}},
+		{"on later line", "<a>\n</a>\n  </a>", "unexpected closing tag </a> with no matching open", Position{Line: 3, Column: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.input).Parse()
+			parseErr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("expected ParseError, got %v", err)
+			}
+			if parseErr.Message != tt.message {
+				t.Errorf("expected message %q, got %q", tt.message, parseErr.Message)
+			}
+			if parseErr.Position.Line != tt.position.Line || parseErr.Position.Column != tt.position.Column {
+				t.Errorf("expected position %s, got %s", tt.position, parseErr.Position)
+			}
+		})
+	}
+}
+
+// TestMaxCommentLength 测试单个注释长度限制
+func TestMaxCommentLength(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxCommentLength = 10
+
+	t.Run("over-limit comment rejected", func(t *testing.T) {
+		_, err := NewParserWithConfig("<root><!--0123456789a--></root>", config).Parse()
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("expected ParseError, got %v", err)
+		}
+		if parseErr.Message != "comment exceeds limit of 10 bytes" {
+			t.Errorf("unexpected message %q", parseErr.Message)
+		}
+		if parseErr.Position.Column != 7 {
+			t.Errorf("expected error at comment start, got %v", parseErr.Position)
+		}
+	})
+
+	t.Run("unterminated over-limit comment rejected", func(t *testing.T) {
+		_, err := NewParserWithConfig("<!--"+strings.Repeat("x", 1000), config).Parse()
+		if err == nil || !strings.Contains(err.Error(), "comment exceeds limit") {
+			t.Fatalf("expected limit error, got %v", err)
+		}
+	})
+
+	t.Run("at-limit comment accepted", func(t *testing.T) {
+		doc, err := NewParserWithConfig("<root><!--0123456789--></root>", config).Parse()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		comment := doc.Children[0].(*Element).Children[0].(*Comment)
+		if comment.Content != "0123456789" {
+			t.Errorf("unexpected comment %q", comment.Content)
+		}
+	})
+}
+
+// TestMaxNodes 测试节点数量限制
+func TestMaxNodes(t *testing.T) {
+	input := "<root>" + strings.Repeat("<item/>", 1000) + "</root>"
+
+	t.Run("exceeding limit", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxNodes = 100
+
+		_, err := NewParserWithConfig(input, config).Parse()
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("expected ParseError, got %v", err)
+		}
+		if parseErr.Message != "node count exceeds limit of 100" {
+			t.Errorf("unexpected message %q", parseErr.Message)
+		}
+		// 第 101 个节点是第 100 个 <item/>
+		expectedColumn := len("<root>") + 99*len("<item/>") + 1
+		if parseErr.Position.Column != expectedColumn {
+			t.Errorf("expected column %d, got %d", expectedColumn, parseErr.Position.Column)
+		}
+	})
+
+	t.Run("exactly at limit", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxNodes = 1001
+
+		doc, err := NewParserWithConfig(input, config).Parse()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(doc.Children[0].(*Element).Children) != 1000 {
+			t.Errorf("expected 1000 children")
+		}
+	})
+
+	t.Run("counts all node kinds", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxNodes = 3
+
+		_, err := NewParserWithConfig("<a>text<!-- c --></a>", config).Parse()
+		if err != nil {
+			t.Fatalf("unexpected error at limit: %v", err)
+		}
+		_, err = NewParserWithConfig("<a>text<!-- c --><b/></a>", config).Parse()
+		if err == nil {
+			t.Error("expected error when exceeding limit")
+		}
+	})
+}
+
+// TestCDATAAsText 测试将 CDATA 区段解析为文本节点
+func TestCDATAAsText(t *testing.T) {
+	input := "<p><![CDATA[a<b]]></p>"
+
+	t.Run("default keeps CDATA node", func(t *testing.T) {
+		doc, err := NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		p := doc.Children[0].(*Element)
+		cdata, ok := p.Children[0].(*CDATA)
+		if !ok {
+			t.Fatalf("expected CDATA child, got %T", p.Children[0])
+		}
+		if cdata.Content != "a<b" {
+			t.Errorf("expected content %q, got %q", "a<b", cdata.Content)
+		}
+	})
+
+	t.Run("CDATAAsText yields text node", func(t *testing.T) {
+		config := DefaultConfig()
+		config.CDATAAsText = true
+
+		doc, err := NewParserWithConfig(input, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		p := doc.Children[0].(*Element)
+		if len(p.Children) != 1 {
+			t.Fatalf("expected 1 child, got %d", len(p.Children))
+		}
+		text, ok := p.Children[0].(*Text)
+		if !ok {
+			t.Fatalf("expected Text child, got %T", p.Children[0])
+		}
+		if text.Content != "a<b" {
+			t.Errorf("expected content %q, got %q", "a<b", text.Content)
+		}
+	})
+
+	t.Run("unterminated CDATA reads to EOF", func(t *testing.T) {
+		token := NewLexer("<![CDATA[x<y").NextToken()
+		if token.Type != TokenCDATA || token.Value != "x<y" {
+			t.Errorf("expected CDATA %q, got %v %q", "x<y", token.Type, token.Value)
+		}
+	})
+}
+
+// TestParserStats 测试解析统计信息
+func TestParserStats(t *testing.T) {
+	input := `<root a="1">hello<!-- c --><child><leaf/>text</child><br/></root><!-- tail -->`
+
+	parser := NewParser(input)
+	if _, err := parser.Parse(); err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	expected := ParseStats{
+		Elements: 4,
+		Texts:    2,
+		Comments: 2,
+		MaxDepth: 3,
+		Tokens:   10,
+	}
+	if stats := parser.Stats(); stats != expected {
+		t.Errorf("expected stats %+v, got %+v", expected, stats)
+	}
+
+	t.Run("skipped comments are consumed but not counted", func(t *testing.T) {
+		config := DefaultConfig()
+		config.SkipComments = true
+
+		parser := NewParserWithConfig("<a><!-- c --><b/></a>", config)
+		if _, err := parser.Parse(); err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		expected := ParseStats{Elements: 2, MaxDepth: 2, Tokens: 4}
+		if stats := parser.Stats(); stats != expected {
+			t.Errorf("expected stats %+v, got %+v", expected, stats)
+		}
+	})
+}
+
+// TestAttributeValueDecoder 测试自定义属性值解码器
+func TestAttributeValueDecoder(t *testing.T) {
+	t.Run("decodes values", func(t *testing.T) {
+		config := DefaultConfig()
+		config.DecodeEntities = true
+		config.AttributeValueDecoder = func(name, value string) (string, error) {
+			return strings.ReplaceAll(value, "&amp;", "&"), nil
+		}
+
+		doc, err := NewParserWithConfig(`<a title="a &amp;amp; b" href="?x=1&amp;y=2" hidden>&amp;</a>`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		a := doc.Children[0].(*Element)
+		if a.Attributes["title"] != "a &amp; b" {
+			t.Errorf("expected single decoding, got %q", a.Attributes["title"])
+		}
+		if a.Attributes["href"] != "?x=1&y=2" {
+			t.Errorf("unexpected href %q", a.Attributes["href"])
+		}
+		if _, ok := a.Attributes["hidden"]; !ok {
+			t.Error("expected boolean attribute to be kept")
+		}
+		if a.Children[0].(*Text).Content != "&" {
+			t.Errorf("expected text entities to follow DecodeEntities, got %q", a.Children[0].(*Text).Content)
+		}
+	})
+
+	t.Run("errors become parse errors", func(t *testing.T) {
+		config := DefaultConfig()
+		config.AttributeValueDecoder = func(name, value string) (string, error) {
+			if name == "width" && strings.TrimLeft(value, "0123456789") != "" {
+				return "", fmt.Errorf("not a number")
+			}
+			return value, nil
+		}
+
+		_, err := NewParserWithConfig(`<img src="a.png" width="wide"/>`, config).Parse()
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("expected ParseError, got %v", err)
+		}
+		if parseErr.Message != `invalid value for attribute "width": not a number` {
+			t.Errorf("unexpected message %q", parseErr.Message)
+		}
+	})
+}
+
+// TestAttributeValueSink 测试超大属性值的流式处理
+func TestAttributeValueSink(t *testing.T) {
+	payload := "data:image/png;base64," + strings.Repeat("QUJD", 2048)
+	input := `<p><img alt="small" src="` + payload + `" title='it\'s'/>text</p>`
+
+	t.Run("sink receives full value", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxAttributeValueBytes = 64
+		received := map[string]string{}
+		config.AttributeValueSink = func(name string, r io.Reader) {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				t.Errorf("read error: %v", err)
+			}
+			received[name] = string(data)
+		}
+
+		doc, err := NewParserWithConfig(input, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		p := doc.Children[0].(*Element)
+		img := p.Children[0].(*Element)
+		if img.Attributes["src"] != StreamedAttributeValue {
+			t.Errorf("expected placeholder, got %d bytes", len(img.Attributes["src"]))
+		}
+		if img.Attributes["alt"] != "small" || img.Attributes["title"] != "it's" {
+			t.Errorf("unexpected small attributes %v", img.Attributes)
+		}
+		if len(received) != 1 || received["src"] != payload {
+			t.Errorf("expected sink to receive the full src value, got %d values", len(received))
+		}
+		if p.Children[1].(*Text).Content != "text" {
+			t.Errorf("expected parsing to continue after streamed value, got %v", p.Children[1])
+		}
+	})
+
+	t.Run("escaped and unquoted values", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxAttributeValueBytes = 4
+		received := map[string]string{}
+		config.AttributeValueSink = func(name string, r io.Reader) {
+			data, _ := io.ReadAll(r)
+			received[name] = string(data)
+		}
+
+		_, err := NewParserWithConfig(`<a q="say \"hi\"" u=abcdefgh/>`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		if received["q"] != `say "hi"` || received["u"] != "abcdefgh" {
+			t.Errorf("unexpected sink values %q", received)
+		}
+	})
+
+	t.Run("limit without sink", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxAttributeValueBytes = 64
+
+		_, err := NewParserWithConfig(input, config).Parse()
+		if err == nil || !strings.Contains(err.Error(), `value of attribute "src" exceeds limit of 64 bytes`) {
+			t.Errorf("expected limit error, got %v", err)
+		}
+	})
+}
+
+// TestKeepLeadingComment 测试跳过注释时保留开头的许可证注释
+func TestKeepLeadingComment(t *testing.T) {
+	input := "<!-- License: MIT -->\n<!-- second -->\n<root><!-- inner --><item/></root>\n<!-- trailing -->"
+
+	config := DefaultConfig()
+	config.SkipComments = true
+	config.KeepLeadingComment = true
+
+	doc, err := NewParserWithConfig(input, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(doc.Children) != 2 {
+		t.Fatalf("expected leading comment and root, got %d children", len(doc.Children))
+	}
+	comment, ok := doc.Children[0].(*Comment)
+	if !ok || comment.Content != "License: MIT" {
+		t.Errorf("expected leading license comment, got %v", doc.Children[0])
+	}
+	root := doc.Children[1].(*Element)
+	if len(root.Children) != 1 {
+		t.Errorf("expected inner comment to be skipped, got %d children", len(root.Children))
+	}
+
+	t.Run("without KeepLeadingComment", func(t *testing.T) {
+		config := DefaultConfig()
+		config.SkipComments = true
+		doc, _ := NewParserWithConfig(input, config).Parse()
+		if len(doc.Children) != 1 {
+			t.Errorf("expected only root, got %d children", len(doc.Children))
+		}
+	})
+
+	t.Run("no leading comment", func(t *testing.T) {
+		doc, _ := NewParserWithConfig("<root/><!-- after -->", config).Parse()
+		if len(doc.Children) != 1 {
+			t.Errorf("expected only root, got %d children", len(doc.Children))
+		}
+	})
+}
+
+// TestCloseTagPositions 测试结束标签位置记录
+func TestCloseTagPositions(t *testing.T) {
+	input := "<root>\n  <item><b>x</b></item>\n</root>"
+	config := DefaultConfig()
+	config.TrackCloseTagPositions = true
+
+	doc, err := NewParserWithConfig(input, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	root := doc.Children[0].(*Element)
+	item := root.Children[0].(*Element)
+	b := item.Children[0].(*Element)
+
+	tests := []struct {
+		elem   *Element
+		line   int
+		column int
+	}{
+		{root, 3, 1},
+		{item, 2, 17},
+		{b, 2, 13},
+	}
+	for _, tt := range tests {
+		pos := tt.elem.CloseTagPos
+		if pos.Line != tt.line || pos.Column != tt.column {
+			t.Errorf("<%s>: expected close tag at %d:%d, got %s", tt.elem.TagName, tt.line, tt.column, pos)
+		}
+		if closeTag := "</" + tt.elem.TagName + ">"; !strings.HasPrefix(input[pos.ByteOffset-1:], closeTag) {
+			t.Errorf("<%s>: offset %d does not point at %s", tt.elem.TagName, pos.ByteOffset, closeTag)
+		}
+	}
+
+	t.Run("name position on token", func(t *testing.T) {
+		config := DefaultConfig()
+		config.TrackCloseTagPositions = true
+		config.LenientCloseTags = true
+		lexer := NewLexerWithConfig("<a></ a>", config)
+		lexer.NextToken()
+		token := lexer.NextToken()
+		if token.Type != TokenCloseTag || token.Position.Column != 4 || token.NamePos.Column != 7 {
+			t.Errorf("unexpected close tag token %v at %s, name at %s", token.Type, token.Position, token.NamePos)
+		}
+	})
+
+	t.Run("not tracked by default", func(t *testing.T) {
+		doc, err := NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		if pos := doc.Children[0].(*Element).CloseTagPos; pos != (Position{}) {
+			t.Errorf("expected zero position, got %s", pos)
+		}
+	})
+}
+
+// TestValidateUTF8 测试解析阶段的 UTF-8 校验
+func TestValidateUTF8(t *testing.T) {
+	input := "<root>\n  <p>ok " + string([]byte{0xff, 0xfe}) + "</p>\n</root>"
+
+	t.Run("rejected at parse time", func(t *testing.T) {
+		config := DefaultConfig()
+		config.ValidateUTF8 = true
+		_, err := NewParserWithConfig(input, config).Parse()
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("expected ParseError, got %v", err)
+		}
+		if parseErr.Message != "invalid UTF-8 encoding" {
+			t.Errorf("unexpected message %q", parseErr.Message)
+		}
+		if parseErr.Position.Line != 2 || parseErr.Position.Column != 9 {
+			t.Errorf("expected error at 2:9, got %s", parseErr.Position)
+		}
+	})
+
+	t.Run("accepted by default", func(t *testing.T) {
+		if _, err := NewParser(input).Parse(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("valid multibyte input", func(t *testing.T) {
+		config := DefaultConfig()
+		config.ValidateUTF8 = true
+		if _, err := NewParserWithConfig("<p title=\"é\">日本🎉</p>", config).Parse(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+// TestElementAttributePositions 测试元素上记录的属性位置
+func TestElementAttributePositions(t *testing.T) {
+	input := "<root>\n  <input\n    type=\"text\"\n      name='q' required/>\n  <div   class=\"c\"></div>\n</root>"
+	config := DefaultConfig()
+	config.TrackAttributePositions = true
+
+	doc, err := NewParserWithConfig(input, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	root := doc.Children[0].(*Element)
+	input0 := root.Children[0].(*Element)
+	div := root.Children[1].(*Element)
+
+	tests := []struct {
+		elem   *Element
+		name   string
+		line   int
+		column int
+	}{
+		{input0, "type", 3, 5},
+		{input0, "name", 4, 7},
+		{input0, "required", 4, 16},
+		{div, "class", 5, 10},
+	}
+	for _, tt := range tests {
+		pos, ok := tt.elem.AttributePos[tt.name]
+		if !ok {
+			t.Errorf("missing position for %s", tt.name)
+			continue
+		}
+		if pos.Line != tt.line || pos.Column != tt.column {
+			t.Errorf("%s: expected %d:%d, got %s", tt.name, tt.line, tt.column, pos)
+		}
+		if !strings.HasPrefix(input[pos.ByteOffset-1:], tt.name) {
+			t.Errorf("%s: offset %d does not point at the attribute name", tt.name, pos.ByteOffset)
+		}
+	}
+
+	if root.AttributePos != nil {
+		t.Errorf("expected no positions for attribute-free element, got %v", root.AttributePos)
+	}
+
+	plain, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if pos := plain.Children[0].(*Element).Children[0].(*Element).AttributePos; pos != nil {
+		t.Errorf("expected nil positions by default, got %v", pos)
+	}
+}
+
+// TestEmptyTags 测试 <> 片段标签
+func TestEmptyTags(t *testing.T) {
+	t.Run("error by default", func(t *testing.T) {
+		tests := []struct {
+			input   string
+			message string
+			column  int
+		}{
+			{"<>content</>", "empty tag <>", 1},
+			{"<a>x</></a>", "empty tag </>", 5},
+		}
+		for _, tt := range tests {
+			_, err := NewParser(tt.input).Parse()
+			parseErr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("%s: expected ParseError, got %v", tt.input, err)
+			}
+			if parseErr.Message != tt.message || parseErr.Position.Column != tt.column {
+				t.Errorf("%s: expected %q at column %d, got %q at %s", tt.input, tt.message, tt.column, parseErr.Message, parseErr.Position)
+			}
+		}
+	})
+
+	t.Run("fragment nodes", func(t *testing.T) {
+		config := DefaultConfig()
+		config.AllowEmptyTags = true
+		var completed []string
+		config.OnElement = func(e *Element) { completed = append(completed, e.TagName) }
+
+		parser := NewParserWithConfig(`<><a>1</a><>nested</></><root><><b/></></root>`, config)
+		doc, err := parser.Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		// 片段占位元素不计入元素数量和嵌套深度
+		if stats := parser.Stats(); stats.Elements != 3 || stats.MaxDepth != 2 || stats.Texts != 2 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+		if len(doc.Children) != 2 {
+			t.Fatalf("expected 2 top-level nodes, got %d", len(doc.Children))
+		}
+
+		top, ok := doc.Children[0].(*Fragment)
+		if !ok {
+			t.Fatalf("expected Fragment, got %T", doc.Children[0])
+		}
+		if len(top.Children) != 2 || top.Children[0].(*Element).Parent != top {
+			t.Errorf("unexpected fragment children %v", top.Children)
+		}
+		if inner, ok := top.Children[1].(*Fragment); !ok || inner.Children[0].(*Text).Content != "nested" {
+			t.Errorf("expected nested fragment, got %v", top.Children[1])
+		}
+
+		root := doc.Children[1].(*Element)
+		if _, ok := root.Children[0].(*Fragment); !ok {
+			t.Errorf("expected fragment inside <root>, got %T", root.Children[0])
+		}
+		if strings.Join(completed, ",") != "a,b,root" {
+			t.Errorf("fragments should not be reported to OnElement, got %v", completed)
+		}
+
+		output, err := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		if output != `<><a>1</a><>nested</></><root><><b /></></root>` {
+			t.Errorf("unexpected output %s", output)
+		}
+	})
+}
+
+// TestAllowAnonymousClose 测试 </> 闭合当前元素
+func TestAllowAnonymousClose(t *testing.T) {
+	config := DefaultConfig()
+	config.AllowAnonymousClose = true
+
+	doc, err := NewParserWithConfig(`<div>text</><ul><li>a</><li>b</></ul>`, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	div := doc.Children[0].(*Element)
+	if div.TagName != "div" || len(div.Children) != 1 || div.Children[0].(*Text).Content != "text" {
+		t.Errorf("expected <div> with text child, got %s", PrettyPrint(div))
+	}
+	ul := doc.Children[1].(*Element)
+	if len(ul.Children) != 2 {
+		t.Errorf("expected 2 list items, got %d", len(ul.Children))
+	}
+
+	if _, err := NewParserWithConfig(`</>`, config).Parse(); err == nil {
+		t.Error("expected error for anonymous close without open element")
+	}
+	if _, err := NewParserWithConfig(`<>x</>`, config).Parse(); err == nil {
+		t.Error("expected <> to remain an error without AllowEmptyTags")
+	}
+
+	_, err = NewParser(`<div>text</>`).Parse()
+	if err == nil || !strings.Contains(err.Error(), "empty tag </>") {
+		t.Errorf("expected empty tag error without the flag, got %v", err)
+	}
+}
+
+// TestCaseInsensitiveCloseTags 测试大小写不敏感模式下结束标签的匹配
+func TestCaseInsensitiveCloseTags(t *testing.T) {
+	htmlConfig := HTMLConfig()
+	htmlConfig.AllowVoidElementClose = true
+	for _, input := range []string{`<DIV></div>`, `<span></SPAN>`, `<Ul><li>x</LI></uL>`, `<p>a<BR></br>b</p>`} {
+		if _, err := NewParserWithConfig(input, htmlConfig).Parse(); err != nil {
+			t.Errorf("%s: expected to parse under HTML config, got %v", input, err)
+		}
+	}
+
+	doc, _ := NewParserWithConfig(`<p>a<BR></br>b</p>`, htmlConfig).Parse()
+	if p := doc.Children[0].(*Element); len(p.Children) != 3 {
+		t.Errorf("expected </br> to be skipped after <BR>, got %d children", len(p.Children))
+	}
+
+	for _, input := range []string{`<DIV></div>`, `<span></SPAN>`} {
+		_, err := NewParserWithConfig(input, XMLConfig()).Parse()
+		if err == nil || !strings.Contains(err.Error(), "mismatched tags") {
+			t.Errorf("%s: expected mismatched tags error under XML config, got %v", input, err)
+		}
+	}
+}
+
+// TestTagNameRewriter 测试解析时重写标签名
+func TestTagNameRewriter(t *testing.T) {
+	config := DefaultConfig()
+	config.TagNameRewriter = func(name string) string {
+		if name == "b" {
+			return "strong"
+		}
+		return name
+	}
+
+	doc, err := NewParserWithConfig(`<p><b>x</b><i>y</i><b/></p>`, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	p := doc.Children[0].(*Element)
+	var tags []string
+	for _, child := range p.Children {
+		tags = append(tags, child.(*Element).TagName)
+	}
+	if strings.Join(tags, ",") != "strong,i,strong" {
+		t.Errorf("expected rewritten tags, got %v", tags)
+	}
+	if text, _ := p.Children[0].(*Element).FirstText(); text != "x" {
+		t.Errorf("expected text child preserved, got %q", text)
+	}
+
+	// 结束标签同样被重写，与原始开始标签名不匹配的结束标签仍然报错
+	if _, err := NewParserWithConfig(`<b>x</strong>`, config).Parse(); err != nil {
+		t.Errorf("expected </strong> to close rewritten <b>, got %v", err)
+	}
+	if _, err := NewParserWithConfig(`<strong>x</i>`, config).Parse(); err == nil {
+		t.Error("expected mismatched tags error")
+	}
+
+	// 大小写不敏感时重写函数收到规范化后的名称，大小写不同的结束标签仍能匹配
+	htmlConfig := HTMLConfig()
+	htmlConfig.TagNameRewriter = config.TagNameRewriter
+	doc, err = NewParserWithConfig(`<p><B>x</b><I>y</i></p>`, htmlConfig).Parse()
+	if err != nil {
+		t.Fatalf("case-insensitive parse error: %v", err)
+	}
+	p = doc.Children[0].(*Element)
+	if got := p.Children[0].(*Element).TagName; got != "strong" {
+		t.Errorf("expected <B> rewritten to strong, got %q", got)
+	}
+	if got := p.Children[1].(*Element).TagName; got != "I" {
+		t.Errorf("expected unrewritten tag to keep its case, got %q", got)
+	}
+}
+
+// TestSkipManyComments 测试大量连续注释在跳过时不会逐个递归
+func TestSkipManyComments(t *testing.T) {
+	comments := strings.Repeat("<!-- c -->", 10000)
+
+	config := DefaultConfig()
+	config.SkipComments = true
+	config.KeepLeadingComment = true
+
+	doc, err := NewParserWithConfig(comments+"<a/>"+comments+"<b/>"+comments, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(doc.Children) != 3 {
+		t.Fatalf("expected leading comment and 2 elements, got %d nodes", len(doc.Children))
+	}
+	if _, ok := doc.Children[0].(*Comment); !ok {
+		t.Errorf("expected the leading comment to be kept, got %T", doc.Children[0])
+	}
+	if doc.Children[1].(*Element).TagName != "a" || doc.Children[2].(*Element).TagName != "b" {
+		t.Errorf("unexpected elements %v", doc.Children[1:])
+	}
+
+	config.KeepLeadingComment = false
+	doc, err = NewParserWithConfig(comments, config).Parse()
+	if err != nil || len(doc.Children) != 0 {
+		t.Errorf("expected empty document for comments-only input, got %v, %v", doc, err)
+	}
+}
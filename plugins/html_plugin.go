@@ -1,6 +1,7 @@
 package plugins
 
 import (
+	"sort"
 	"strings"
 )
 
@@ -80,27 +81,37 @@ func (hap *HTMLAttributeProcessor) ProcessAttribute(key, value string) (string,
 
 // IsBooleanAttribute 检查是否是HTML布尔属性 - 实现核心接口
 func (hap *HTMLAttributeProcessor) IsBooleanAttribute(key string) bool {
-	// HTML5标准布尔属性
-	booleanAttrs := map[string]bool{
-		"autofocus": true,
-		"autoplay":  true,
-		"checked":   true,
-		"controls":  true,
-		"defer":     true,
-		"disabled":  true,
-		"hidden":    true,
-		"loop":      true,
-		"multiple":  true,
-		"muted":     true,
-		"open":      true,
-		"readonly":  true,
-		"required":  true,
-		"reversed":  true,
-		"scoped":    true,
-		"selected":  true,
+	return html5BooleanAttributes[strings.ToLower(key)]
+}
+
+// BooleanAttributeNames 返回按名称排序的HTML布尔属性列表
+func (hap *HTMLAttributeProcessor) BooleanAttributeNames() []string {
+	names := make([]string, 0, len(html5BooleanAttributes))
+	for name := range html5BooleanAttributes {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	return booleanAttrs[strings.ToLower(key)]
+// html5BooleanAttributes HTML5标准布尔属性
+var html5BooleanAttributes = map[string]bool{
+	"autofocus": true,
+	"autoplay":  true,
+	"checked":   true,
+	"controls":  true,
+	"defer":     true,
+	"disabled":  true,
+	"hidden":    true,
+	"loop":      true,
+	"multiple":  true,
+	"muted":     true,
+	"open":      true,
+	"readonly":  true,
+	"required":  true,
+	"reversed":  true,
+	"scoped":    true,
+	"selected":  true,
 }
 
 // NewHTMLAttributeProcessor 创建HTML属性处理器
@@ -1,6 +1,7 @@
 package markit
 
 import (
+	"io"
 	"strings"
 )
 
@@ -21,8 +22,103 @@ type ParserConfig struct {
 	AllowEmptyElements bool
 	AllowSelfCloseTags bool // 是否允许自封闭标签
 
+	// KeepLeadingComment 与 SkipComments 同时开启时保留文档开头的第一个注释（如许可证声明）
+	KeepLeadingComment bool
+
+	// DecodeEntities 是否解码文本和属性值中的预定义实体和数字字符引用
+	DecodeEntities bool
+
+	// OnUnknownEntity 在 DecodeEntities 开启时解析无法识别的命名实体（如 &widget;），
+	// 返回 (value, true) 时替换为 value，返回 false 时原样保留
+	OnUnknownEntity func(name string) (string, bool)
+
+	// TrimAttributeValues 是否修剪属性值首尾的空白（如 class=" foo " 读取为 foo）
+	TrimAttributeValues bool
+
+	// AllowBacktickAttributes 是否允许以反引号包裹属性值（如 attr=`value`），反引号内容不处理转义
+	AllowBacktickAttributes bool
+
+	// AttributeValueDecoder 在词法分析时解码属性值，返回的错误会作为解析错误报告
+	// 设置后取代 DecodeEntities 对属性值的解码，避免重复解码
+	AttributeValueDecoder func(name, value string) (string, error)
+
+	// MaxAttributeValueBytes 单个属性值允许的最大字节数（0 表示不限制）
+	// 超出时若设置了 AttributeValueSink 则流式交给它处理，否则报错
+	MaxAttributeValueBytes int
+
+	// AttributeValueSink 接收超过 MaxAttributeValueBytes 的原始属性值，AST 中保存 StreamedAttributeValue 占位值
+	AttributeValueSink func(name string, r io.Reader)
+
+	// NormalizeLineEndings 是否在词法分析前将 \r\n 和 \r 统一为 \n
+	NormalizeLineEndings bool
+
+	// ValidateUTF8 是否在词法分析时拒绝非法 UTF-8 字节，报告其位置
+	ValidateUTF8 bool
+
+	// MaxNodes 允许创建的最大节点数量（0 表示不限制）
+	MaxNodes int
+
+	// IsWhitespace 自定义空白字符判定，用于跳过标记间空白和修剪文本（nil 表示使用 unicode.IsSpace）
+	IsWhitespace func(rune) bool
+
+	// MaxInputBytes 允许的最大输入字节数（0 表示不限制）
+	MaxInputBytes int
+
+	// MaxCommentLength 单个注释内容允许的最大字节数（0 表示不限制）
+	MaxCommentLength int
+
+	// TrimCommentWhitespace 是否修剪注释内容的首尾空白（nil 表示跟随 TrimWhitespace）
+	TrimCommentWhitespace *bool
+
+	// UseRuneOffsets 是否以字符（码点）而非字节计算 Position.Offset，字节偏移保留在 ByteOffset 中
+	UseRuneOffsets bool
+
+	// TrackAttributePositions 是否在标签 token 中记录每个属性的位置（默认关闭以避免热路径开销）
+	TrackAttributePositions bool
+
+	// TrackCloseTagPositions 是否在结束标签 token 中记录标签名位置，并在元素上记录结束标签位置（Element.CloseTagPos）
+	TrackCloseTagPositions bool
+
 	// Void Elements 配置
 	VoidElements map[string]bool // 定义哪些标签是 void element（如 HTML 的 br, hr, img 等）
+
+	// ErrorOnVoidElementClose 遇到 void element 的结束标签（如 <br></br>）时报告带位置的错误，
+	// 优先于 AllowVoidElementClose
+	ErrorOnVoidElementClose bool
+	// AllowVoidElementClose 跳过紧跟在 void element 之后的同名结束标签，默认将其作为多余的结束标签报错
+	AllowVoidElementClose bool
+
+	// NamespaceAware 是否在解析时记录每个元素作用域内的命名空间绑定
+	NamespaceAware bool
+
+	// ParsePseudoAttributes 是否将处理指令内容解析为伪属性
+	ParsePseudoAttributes bool
+
+	// CDATAAsText 是否将 CDATA 区段解析为内容原样的 Text 节点
+	CDATAAsText bool
+
+	// AllowEmptyTags 是否将 <> 和 </> 解析为片段节点（Fragment），默认报告 empty tag 错误
+	AllowEmptyTags bool
+
+	// AllowAnonymousClose 是否允许 </> 闭合当前打开的元素而不检查标签名
+	AllowAnonymousClose bool
+
+	// TagNameRewriter 在词法分析时重写标签名（如将已废弃的 center 改写为 div），开始和结束标签同样重写；
+	// CaseSensitive 关闭时传入的是小写后的名称
+	TagNameRewriter func(name string) string
+
+	// LenientCloseTags 是否允许结束标签中 "</" 与标签名之间出现空白（如 </ div>），
+	// 标签名与结束序列之间的空白（如 </div >）始终允许
+	LenientCloseTags bool
+
+	// LenientAttributes 是否跳过格式错误的属性并继续读取标签（默认整个标签报错）
+	LenientAttributes bool
+
+	// OnWarning 解析过程中遇到可恢复问题时的回调
+	OnWarning func(pos Position, message string)
+
+	// OnElement 元素（及其子树）解析完成后的回调，按后序顺序调用
+	OnElement func(*Element)
 }
 
 // DefaultConfig 创建默认配置
@@ -41,6 +137,26 @@ func DefaultConfig() *ParserConfig {
 	return config
 }
 
+// Clone 返回配置的深拷贝，修改副本的 void elements 或协议不会影响原配置
+// AttributeProcessor 和回调函数按引用共享
+func (config *ParserConfig) Clone() *ParserConfig {
+	clone := *config
+	if config.CoreMatcher != nil {
+		clone.CoreMatcher = config.CoreMatcher.Clone()
+	}
+	if config.VoidElements != nil {
+		clone.VoidElements = make(map[string]bool, len(config.VoidElements))
+		for tag, void := range config.VoidElements {
+			clone.VoidElements[tag] = void
+		}
+	}
+	if config.TrimCommentWhitespace != nil {
+		trim := *config.TrimCommentWhitespace
+		clone.TrimCommentWhitespace = &trim
+	}
+	return &clone
+}
+
 // IsVoidElement 检查指定标签是否是 void element
 func (config *ParserConfig) IsVoidElement(tagName string) bool {
 	if config.VoidElements == nil {
@@ -52,6 +168,20 @@ func (config *ParserConfig) IsVoidElement(tagName string) bool {
 	return config.VoidElements[normalizedTagName]
 }
 
+// VoidElementNames 返回按名称排序的 void element 列表
+func (config *ParserConfig) VoidElementNames() []string {
+	return sortedKeys(config.VoidElements)
+}
+
+// BooleanAttributeNames 返回属性处理器声明的布尔属性列表（按名称排序）
+// 属性处理器未提供 BooleanAttributeNames 方法时返回 nil
+func (config *ParserConfig) BooleanAttributeNames() []string {
+	if lister, ok := config.AttributeProcessor.(interface{ BooleanAttributeNames() []string }); ok {
+		return lister.BooleanAttributeNames()
+	}
+	return nil
+}
+
 // AddVoidElement 添加 void element
 func (config *ParserConfig) AddVoidElement(tagName string) {
 	if config.VoidElements == nil {
@@ -79,6 +209,22 @@ func (config *ParserConfig) SetVoidElements(elements []string) {
 	}
 }
 
+// shouldTrimComment 判断是否需要修剪注释内容的空白字符
+func (config *ParserConfig) shouldTrimComment() bool {
+	if config.TrimCommentWhitespace != nil {
+		return *config.TrimCommentWhitespace
+	}
+	return config.TrimWhitespace
+}
+
+// namesEqual 按大小写敏感性配置比较两个名称（如开始标签与结束标签）
+func (config *ParserConfig) namesEqual(a, b string) bool {
+	if !config.CaseSensitive {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
 // NormalizeCase 根据配置标准化大小写
 func (config *ParserConfig) NormalizeCase(s string) string {
 	if !config.CaseSensitive {
@@ -1,6 +1,7 @@
 package markit
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -340,3 +341,76 @@ func TestProtocolEdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// TestCustomTagSyntax 测试自定义标准标签定界符
+func TestCustomTagSyntax(t *testing.T) {
+	config := DefaultConfig()
+	config.CoreMatcher = NewCoreProtocolMatcherWithTagSyntax("[", "]")
+
+	t.Run("BBCode style tags", func(t *testing.T) {
+		doc, err := NewParserWithConfig(`[b]bold[/b] and [url href="http://x.y/z"]link[/url][br/]`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		if len(doc.Children) != 4 {
+			t.Fatalf("expected 4 children, got %d: %s", len(doc.Children), PrettyPrint(doc))
+		}
+
+		b := doc.Children[0].(*Element)
+		if b.TagName != "b" || b.Children[0].(*Text).Content != "bold" {
+			t.Errorf("unexpected bold element: %s", PrettyPrint(b))
+		}
+		if text := doc.Children[1].(*Text); text.Content != "and" {
+			t.Errorf("unexpected text %q", text.Content)
+		}
+		url := doc.Children[2].(*Element)
+		if url.TagName != "url" || url.Attributes["href"] != "http://x.y/z" {
+			t.Errorf("unexpected url element: %s", PrettyPrint(url))
+		}
+		if br := doc.Children[3].(*Element); br.TagName != "br" || !br.SelfClose {
+			t.Errorf("unexpected br element: %s", PrettyPrint(br))
+		}
+	})
+
+	t.Run("angle brackets are plain text", func(t *testing.T) {
+		doc, err := NewParserWithConfig(`[p]a <b> c[/p]`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		p := doc.Children[0].(*Element)
+		if text := p.Children[0].(*Text); text.Content != "a <b> c" {
+			t.Errorf("unexpected text %q", text.Content)
+		}
+	})
+
+	t.Run("multi-character delimiters", func(t *testing.T) {
+		config := DefaultConfig()
+		config.CoreMatcher = NewCoreProtocolMatcherWithTagSyntax("<%", "%>")
+
+		doc, err := NewParserWithConfig(`<%if cond="x"%>yes<%/if%>`, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		elem := doc.Children[0].(*Element)
+		if elem.TagName != "if" || elem.Attributes["cond"] != "x" || elem.Children[0].(*Text).Content != "yes" {
+			t.Errorf("unexpected element: %s", PrettyPrint(elem))
+		}
+	})
+
+	t.Run("missing close sequence", func(t *testing.T) {
+		_, err := NewParserWithConfig(`[b`, config).Parse()
+		if err == nil || !strings.Contains(err.Error(), "expected ']'") {
+			t.Errorf("expected missing delimiter error, got %v", err)
+		}
+	})
+
+	t.Run("standard tag protocol", func(t *testing.T) {
+		tag := config.CoreMatcher.StandardTag()
+		if tag == nil || tag.OpenSeq != "[" || tag.CloseSeq != "]" {
+			t.Errorf("unexpected standard tag protocol: %+v", tag)
+		}
+		if NewCoreProtocolMatcher().StandardTag().OpenSeq != "<" {
+			t.Error("default matcher should keep '<'")
+		}
+	})
+}
@@ -97,3 +97,34 @@ func TestNormalizeCase(t *testing.T) {
 		})
 	}
 }
+
+// TestConfigClone 测试配置深拷贝
+func TestConfigClone(t *testing.T) {
+	original := HTMLConfig()
+	clone := original.Clone()
+
+	clone.AddVoidElement("x-icon")
+	clone.RemoveVoidElement("br")
+	clone.CoreMatcher.protocols = append(clone.CoreMatcher.protocols, CoreProtocol{Name: "custom", OpenSeq: "{{", CloseSeq: "}}"})
+	clone.CoreMatcher.StandardTag().OpenSeq = "["
+
+	if original.IsVoidElement("x-icon") || !original.IsVoidElement("br") {
+		t.Error("modifying clone void elements affected the original")
+	}
+	if !clone.IsVoidElement("x-icon") || clone.IsVoidElement("br") {
+		t.Error("clone void elements not modified")
+	}
+	if len(original.CoreMatcher.protocols) != 2 {
+		t.Errorf("expected 2 protocols in original, got %d", len(original.CoreMatcher.protocols))
+	}
+	if tag := original.CoreMatcher.StandardTag(); tag.OpenSeq != "<" {
+		t.Errorf("expected original open sequence <, got %s", tag.OpenSeq)
+	}
+	if clone.CaseSensitive != original.CaseSensitive || clone.AttributeProcessor != original.AttributeProcessor {
+		t.Error("clone should keep scalar settings and attribute processor")
+	}
+
+	if _, err := NewParserWithConfig(`<p>a<br>b</p>`, original).Parse(); err != nil {
+		t.Errorf("original config should still parse void elements: %v", err)
+	}
+}
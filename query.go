@@ -0,0 +1,305 @@
+package markit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// errStopWalk 用于提前终止遍历的哨兵错误
+var errStopWalk = errors.New("stop walk")
+
+// elementVisitor 只关心元素节点的访问者
+type elementVisitor struct {
+	visit func(*Element) error
+}
+
+func (v *elementVisitor) VisitDocument(*Document) error                           { return nil }
+func (v *elementVisitor) VisitElement(e *Element) error                           { return v.visit(e) }
+func (v *elementVisitor) VisitText(*Text) error                                   { return nil }
+func (v *elementVisitor) VisitProcessingInstruction(*ProcessingInstruction) error { return nil }
+func (v *elementVisitor) VisitDoctype(*Doctype) error                             { return nil }
+func (v *elementVisitor) VisitCDATA(*CDATA) error                                 { return nil }
+func (v *elementVisitor) VisitComment(*Comment) error                             { return nil }
+
+// WalkElements 按文档顺序（先序）对每个元素调用 fn，跳过其他类型的节点
+// fn 返回错误时立即终止遍历并返回该错误
+func WalkElements(node Node, fn func(*Element) error) error {
+	return Walk(node, &elementVisitor{visit: fn})
+}
+
+// FindFirst 按文档顺序返回第一个满足条件的元素，未找到时返回 nil
+func (d *Document) FindFirst(pred func(*Element) bool) *Element {
+	var found *Element
+	_ = WalkElements(d, func(e *Element) error {
+		if pred(e) {
+			found = e
+			return errStopWalk
+		}
+		return nil
+	})
+	return found
+}
+
+// FindAll 按文档顺序返回所有满足条件的元素
+func (d *Document) FindAll(pred func(*Element) bool) []*Element {
+	var found []*Element
+	_ = WalkElements(d, func(e *Element) error {
+		if pred(e) {
+			found = append(found, e)
+		}
+		return nil
+	})
+	return found
+}
+
+// Encoding 返回 XML 声明中的 encoding，未声明时返回空字符串
+func (d *Document) Encoding() string {
+	return d.declarationAttributes()["encoding"]
+}
+
+// Version 返回 XML 声明中的 version，未声明时返回空字符串
+func (d *Document) Version() string {
+	return d.declarationAttributes()["version"]
+}
+
+// declarationAttributes 解析第一个处理指令子节点作为 XML 声明的伪属性
+func (d *Document) declarationAttributes() map[string]string {
+	for _, child := range d.Children {
+		pi, ok := child.(*ProcessingInstruction)
+		if !ok {
+			continue
+		}
+
+		if !isXMLDeclaration(pi) {
+			return nil
+		}
+		if len(pi.PseudoAttributes) > 0 {
+			return pi.PseudoAttributes
+		}
+		attrs, _ := parsePseudoAttributes(pi.Content)
+		return attrs
+	}
+	return nil
+}
+
+// hasXMLDeclaration 检查文档的第一个处理指令是否为 XML 声明
+func (d *Document) hasXMLDeclaration() bool {
+	for _, child := range d.Children {
+		if pi, ok := child.(*ProcessingInstruction); ok {
+			return isXMLDeclaration(pi)
+		}
+	}
+	return false
+}
+
+// isXMLDeclaration 检查处理指令的目标是否为 xml
+func isXMLDeclaration(pi *ProcessingInstruction) bool {
+	return pi.Target == "xml"
+}
+
+// ElementPath 返回元素从根元素开始的路径，如 html/body/div[2]/p
+// 同名兄弟元素存在时附加从 1 开始的位置序号，依赖 Parent 指针
+func ElementPath(e *Element) string {
+	var segments []string
+	for e != nil {
+		segment := e.TagName
+		if e.Parent != nil {
+			if index, count := siblingIndex(e); count > 1 {
+				segment = fmt.Sprintf("%s[%d]", e.TagName, index)
+			}
+		}
+		segments = append(segments, segment)
+
+		parent, _ := e.Parent.(*Element)
+		e = parent
+	}
+
+	for i, j := 0, len(segments)-1; i < j; i, j = i+1, j-1 {
+		segments[i], segments[j] = segments[j], segments[i]
+	}
+	return strings.Join(segments, "/")
+}
+
+// siblingIndex 返回元素在同名兄弟元素中的位置（从 1 开始）和同名兄弟元素总数
+func siblingIndex(e *Element) (index, count int) {
+	var siblings []Node
+	switch p := e.Parent.(type) {
+	case *Element:
+		siblings = p.Children
+	case *Fragment:
+		siblings = p.Children
+	case *Document:
+		siblings = p.Children
+	}
+
+	for _, sibling := range siblings {
+		if elem, ok := sibling.(*Element); ok && elem.TagName == e.TagName {
+			count++
+			if elem == e {
+				index = count
+			}
+		}
+	}
+	return index, count
+}
+
+// ReplaceTextContent 将子树内所有文本节点中的 old 替换为 new，返回内容发生变化的文本节点数
+func (e *Element) ReplaceTextContent(old, new string) int {
+	return e.replaceText(func(s string) string { return strings.ReplaceAll(s, old, new) })
+}
+
+// ReplaceTextRegexp 将子树内所有文本节点中匹配 re 的部分替换为 repl（支持 $1 等展开），返回内容发生变化的文本节点数
+func (e *Element) ReplaceTextRegexp(re *regexp.Regexp, repl string) int {
+	return e.replaceText(func(s string) string { return re.ReplaceAllString(s, repl) })
+}
+
+// replaceText 对子树内的文本节点应用替换函数
+func (e *Element) replaceText(replace func(string) string) int {
+	changed := 0
+	for _, child := range e.Children {
+		switch n := child.(type) {
+		case *Text:
+			if content := replace(n.Content); content != n.Content {
+				n.Content = content
+				changed++
+			}
+		case *Element:
+			changed += n.replaceText(replace)
+		}
+	}
+	return changed
+}
+
+// compoundSelector 由标签名、id 和类名组成的简单 CSS 选择器，如 div#main.note
+type compoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+}
+
+// matches 检查元素是否满足选择器的所有条件
+func (s compoundSelector) matches(e *Element) bool {
+	if s.tag != "" && s.tag != "*" && s.tag != e.TagName {
+		return false
+	}
+	if s.id != "" && e.Attributes["id"] != s.id {
+		return false
+	}
+	if len(s.classes) > 0 {
+		classes := strings.Fields(e.Attributes["class"])
+		for _, class := range s.classes {
+			if !containsString(classes, class) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compileSelector 解析逗号分隔的选择器列表，支持标签名、*、#id 和 .class 及其组合，
+// 不支持组合符（如后代选择器）和属性选择器
+func compileSelector(selector string) (func(*Element) bool, error) {
+	var group []compoundSelector
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("invalid selector %q: empty selector", selector)
+		}
+
+		var sel compoundSelector
+		i := 0
+		if part[0] == '*' {
+			sel.tag = "*"
+			i++
+		}
+		for i < len(part) {
+			prefix := byte(0)
+			if part[i] == '#' || part[i] == '.' {
+				prefix = part[i]
+				i++
+			}
+			start := i
+			for i < len(part) && part[i] != '#' && part[i] != '.' {
+				if part[i] < utf8.RuneSelf && !isIdentifierChar(rune(part[i])) {
+					return nil, fmt.Errorf("invalid selector %q: unsupported character %q", selector, part[i])
+				}
+				i++
+			}
+			name := part[start:i]
+			if name == "" {
+				return nil, fmt.Errorf("invalid selector %q: missing name", selector)
+			}
+			switch {
+			case prefix == '#':
+				sel.id = name
+			case prefix == '.':
+				sel.classes = append(sel.classes, name)
+			case start == 0:
+				sel.tag = name
+			default:
+				return nil, fmt.Errorf("invalid selector %q: tag name must come first", selector)
+			}
+		}
+		group = append(group, sel)
+	}
+
+	return func(e *Element) bool {
+		for _, sel := range group {
+			if sel.matches(e) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// containsString 检查切片中是否包含 s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveAll 删除所有匹配 CSS 选择器的元素（连同其子树），返回删除的元素数
+// 嵌套的匹配元素只删除最外层的一个，计数不包含其子树内的匹配
+func (d *Document) RemoveAll(selector string) (int, error) {
+	match, err := compileSelector(selector)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int
+	d.Children, removed = removeMatching(d.Children, match)
+	return removed, nil
+}
+
+// removeMatching 从子节点列表中移除匹配的元素，并递归处理未被移除的元素和片段
+func removeMatching(children []Node, match func(*Element) bool) ([]Node, int) {
+	removed := 0
+	kept := children[:0]
+	for _, child := range children {
+		switch n := child.(type) {
+		case *Element:
+			if match(n) {
+				n.Parent = nil
+				removed++
+				continue
+			}
+			var count int
+			n.Children, count = removeMatching(n.Children, match)
+			removed += count
+		case *Fragment:
+			var count int
+			n.Children, count = removeMatching(n.Children, match)
+			removed += count
+		}
+		kept = append(kept, child)
+	}
+	return kept, removed
+}
@@ -0,0 +1,312 @@
+package markit
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestFindFirstAndFindAll 测试基于谓词的元素查找
+func TestFindFirstAndFindAll(t *testing.T) {
+	input := `<root>
+		<div class="box error" id="a"><p>1</p><p>2</p><p>3</p></div>
+		<div class="error" id="b"><p>1</p></div>
+		<section class="error big" id="c"><p>1</p><p>2</p><p>3</p></section>
+		<div class="ok" id="d"><p>1</p><p>2</p><p>3</p></div>
+	</root>`
+	doc, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	hasClass := func(e *Element, class string) bool {
+		for _, c := range strings.Fields(e.Attributes["class"]) {
+			if c == class {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("combined predicate", func(t *testing.T) {
+		pred := func(e *Element) bool {
+			return hasClass(e, "error") && len(e.Children) > 2
+		}
+
+		first := doc.FindFirst(pred)
+		if first == nil || first.Attributes["id"] != "a" {
+			t.Fatalf("expected element a, got %v", first)
+		}
+
+		all := doc.FindAll(pred)
+		if len(all) != 2 {
+			t.Fatalf("expected 2 matches, got %d", len(all))
+		}
+		if all[0].Attributes["id"] != "a" || all[1].Attributes["id"] != "c" {
+			t.Errorf("unexpected matches: %s, %s", all[0].Attributes["id"], all[1].Attributes["id"])
+		}
+	})
+
+	t.Run("tag name and attribute", func(t *testing.T) {
+		all := doc.FindAll(func(e *Element) bool {
+			return e.TagName == "div" && hasClass(e, "error")
+		})
+		if len(all) != 2 {
+			t.Errorf("expected 2 matches, got %d", len(all))
+		}
+	})
+
+	t.Run("early stop", func(t *testing.T) {
+		calls := 0
+		first := doc.FindFirst(func(e *Element) bool {
+			calls++
+			return e.TagName == "div"
+		})
+		if first == nil || first.Attributes["id"] != "a" {
+			t.Fatalf("expected element a, got %v", first)
+		}
+		// root 和第一个 div
+		if calls != 2 {
+			t.Errorf("expected 2 predicate calls, got %d", calls)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		never := func(*Element) bool { return false }
+		if doc.FindFirst(never) != nil {
+			t.Error("expected nil from FindFirst")
+		}
+		if len(doc.FindAll(never)) != 0 {
+			t.Error("expected no matches from FindAll")
+		}
+	})
+}
+
+// TestWalkElements 测试只访问元素的遍历
+func TestWalkElements(t *testing.T) {
+	doc, err := NewParser(`<a><!-- c --><b>text<c/></b><d><e><f/></e></d></a><g/>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var visited []string
+	if err := WalkElements(doc, func(e *Element) error {
+		visited = append(visited, e.TagName)
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(visited, ","); got != "a,b,c,d,e,f,g" {
+		t.Errorf("expected pre-order a,b,c,d,e,f,g, got %s", got)
+	}
+
+	t.Run("stops on error", func(t *testing.T) {
+		stop := errors.New("stop")
+		var visited []string
+		err := WalkElements(doc, func(e *Element) error {
+			visited = append(visited, e.TagName)
+			if e.TagName == "d" {
+				return stop
+			}
+			return nil
+		})
+		if err != stop {
+			t.Errorf("expected stop error, got %v", err)
+		}
+		if got := strings.Join(visited, ","); got != "a,b,c,d" {
+			t.Errorf("expected traversal to stop at d, got %s", got)
+		}
+	})
+
+	t.Run("non-element root", func(t *testing.T) {
+		called := false
+		_ = WalkElements(&Text{Content: "x"}, func(*Element) error {
+			called = true
+			return nil
+		})
+		if called {
+			t.Error("expected no elements to be visited")
+		}
+	})
+}
+
+// TestElementPath 测试元素路径
+func TestElementPath(t *testing.T) {
+	input := `<html><body>
+		<div id="a"><p>1</p></div>
+		<span/>
+		<div id="b"><p>1</p><section><p id="deep">2</p><p>3</p></section></div>
+	</body></html>`
+	doc, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	tests := []struct {
+		id       string
+		expected string
+	}{
+		{"a", "html/body/div[1]"},
+		{"b", "html/body/div[2]"},
+		{"deep", "html/body/div[2]/section/p[1]"},
+	}
+	for _, tt := range tests {
+		elem := doc.FindFirst(func(e *Element) bool { return e.Attributes["id"] == tt.id })
+		if got := ElementPath(elem); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.id, tt.expected, got)
+		}
+	}
+
+	span := doc.FindFirst(func(e *Element) bool { return e.TagName == "span" })
+	if got := ElementPath(span); got != "html/body/span" {
+		t.Errorf("expected html/body/span, got %q", got)
+	}
+
+	t.Run("parent pointers", func(t *testing.T) {
+		html := doc.Children[0].(*Element)
+		if html.Parent != doc {
+			t.Error("expected root element parent to be the document")
+		}
+		clone := CloneNode(html).(*Element)
+		if clone.Parent != nil || clone.Children[0].(*Element).Parent != clone {
+			t.Error("expected clone to be detached with children pointing to the clone")
+		}
+	})
+
+	t.Run("detached element", func(t *testing.T) {
+		ul := NewElement("ul").WithChildren(NewElement("li"), NewElement("li"))
+		if got := ElementPath(ul.Children[1].(*Element)); got != "ul/li[2]" {
+			t.Errorf("expected ul/li[2], got %q", got)
+		}
+		if got := ElementPath(nil); got != "" {
+			t.Errorf("expected empty path for nil, got %q", got)
+		}
+	})
+}
+
+// TestDocumentDeclaration 测试从 XML 声明读取编码和版本
+func TestDocumentDeclaration(t *testing.T) {
+	pseudo := DefaultConfig()
+	pseudo.ParsePseudoAttributes = true
+
+	tests := []struct {
+		name     string
+		input    string
+		config   *ParserConfig
+		version  string
+		encoding string
+	}{
+		{"double quotes", `<?xml version="1.0" encoding="UTF-16"?><root/>`, DefaultConfig(), "1.0", "UTF-16"},
+		{"single quotes", `<?xml version='1.1' encoding='ISO-8859-1'?><root/>`, DefaultConfig(), "1.1", "ISO-8859-1"},
+		{"mixed quotes", `<?xml version="1.0" encoding='UTF-16'?><root/>`, DefaultConfig(), "1.0", "UTF-16"},
+		{"parsed pseudo attributes", `<?xml version="1.0"?><root/>`, pseudo, "1.0", ""},
+		{"no declaration", `<root/>`, DefaultConfig(), "", ""},
+		{"other processing instruction", `<?xml-stylesheet href="s.xsl" encoding="x"?><root/>`, DefaultConfig(), "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewParserWithConfig(tt.input, tt.config).Parse()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			if got := doc.Version(); got != tt.version {
+				t.Errorf("expected version %q, got %q", tt.version, got)
+			}
+			if got := doc.Encoding(); got != tt.encoding {
+				t.Errorf("expected encoding %q, got %q", tt.encoding, got)
+			}
+		})
+	}
+}
+
+// TestReplaceText 测试子树内文本替换
+func TestReplaceText(t *testing.T) {
+	input := `<root><h1>Hi {{name}}</h1><p>Dear {{name}}, <b>{{name}}</b>!</p><p>no token</p></root>`
+
+	t.Run("ReplaceTextContent", func(t *testing.T) {
+		doc, err := NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		root := doc.Children[0].(*Element)
+
+		if n := root.ReplaceTextContent("{{name}}", "Ada"); n != 3 {
+			t.Errorf("expected 3 changed nodes, got %d", n)
+		}
+		p := root.Children[1].(*Element)
+		if text := p.Children[0].(*Text).Content; text != "Dear Ada," {
+			t.Errorf("expected %q, got %q", "Dear Ada,", text)
+		}
+		if text := p.Children[1].(*Element).Children[0].(*Text).Content; text != "Ada" {
+			t.Errorf("expected %q, got %q", "Ada", text)
+		}
+		if n := root.ReplaceTextContent("{{name}}", "Ada"); n != 0 {
+			t.Errorf("expected no changes on second pass, got %d", n)
+		}
+	})
+
+	t.Run("ReplaceTextRegexp", func(t *testing.T) {
+		doc, err := NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		root := doc.Children[0].(*Element)
+
+		if n := root.ReplaceTextRegexp(regexp.MustCompile(`\{\{(\w+)\}\}`), "<$1>"); n != 3 {
+			t.Errorf("expected 3 changed nodes, got %d", n)
+		}
+		b := root.Children[1].(*Element).Children[1].(*Element)
+		if text := b.Children[0].(*Text).Content; text != "<name>" {
+			t.Errorf("expected <name>, got %q", text)
+		}
+	})
+}
+
+func TestDocumentRemoveAll(t *testing.T) {
+	input := `<html><head><script>a()</script></head><body>` +
+		`<div class="ad top"><p>buy</p><div class="ad">nested</div></div>` +
+		`<p>content</p><script>b()</script><span class="adx">keep</span></body></html>`
+
+	doc, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	removed, err := doc.RemoveAll("script")
+	if err != nil {
+		t.Fatalf("RemoveAll error: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("expected 2 scripts removed, got %d", removed)
+	}
+
+	// 嵌套的 .ad 随外层一起删除，只计数一次
+	removed, err = doc.RemoveAll(".ad")
+	if err != nil {
+		t.Fatalf("RemoveAll error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 outermost .ad removed, got %d", removed)
+	}
+
+	output, _ := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderToString(doc)
+	expected := `<html><head></head><body><p>content</p><span class="adx">keep</span></body></html>`
+	if output != expected {
+		t.Errorf("expected %s, got %s", expected, output)
+	}
+
+	if removed, _ := doc.RemoveAll("span.adx, #missing"); removed != 1 {
+		t.Errorf("expected selector list to remove 1 element, got %d", removed)
+	}
+}
+
+func TestDocumentRemoveAllInvalidSelector(t *testing.T) {
+	doc := &Document{}
+	for _, selector := range []string{"", "div p", "div > p", "[href]", ".", "a,", "#a#"} {
+		if _, err := doc.RemoveAll(selector); err == nil {
+			t.Errorf("expected error for selector %q", selector)
+		}
+	}
+}
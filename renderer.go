@@ -1,10 +1,13 @@
 package markit
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"regexp"
 	"sort"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -14,6 +17,8 @@ type RenderOptions struct {
 	Indent string
 	// EscapeText 是否转义文本内容（默认：true）
 	EscapeText bool
+	// AssumePreEscaped 转义文本和属性值时保留已有的合法实体引用（如未解码实体的解析结果），避免重复转义
+	AssumePreEscaped bool
 	// PreserveSpace 是否保留空白字符
 	PreserveSpace bool
 	// CompactMode 小元素的单行输出模式
@@ -22,8 +27,64 @@ type RenderOptions struct {
 	SortAttributes bool
 	// EmptyElementStyle 空元素的样式
 	EmptyElementStyle EmptyElementStyle
+	// PairSelfClosingWithChildren SelfClose 为 true 但含有子节点的元素（通常为手动构造）按配对标签输出子节点，
+	// 默认按自闭合输出并丢弃子节点，CheckWellFormed 验证会将这种元素报告为错误
+	PairSelfClosingWithChildren bool
 	// IncludeDeclaration 是否包含声明行（如 <?xml...?>, <!DOCTYPE...> 等）
 	IncludeDeclaration bool
+	// ForceXMLDeclaration 文档中没有 XML 声明时在输出开头补充声明
+	ForceXMLDeclaration bool
+	// DeclarationVersion 补充声明的 version（默认："1.0"）
+	DeclarationVersion string
+	// DeclarationEncoding 补充声明的 encoding（默认："UTF-8"）
+	DeclarationEncoding string
+	// NodeFilter 渲染每个节点前调用，render 为 false 时跳过该节点及其子树，
+	// replacement 非 nil 时渲染替换节点（替换节点自身不再经过过滤，其子节点仍会过滤）
+	NodeFilter func(n Node) (render bool, replacement Node)
+	// TextTransform 在转义前对每个文本节点内容进行变换（如智能引号、脱敏）
+	TextTransform func(string) string
+	// InlineElements 内联元素集合，子节点仅由文本和内联元素组成的元素会在单行内渲染
+	InlineElements map[string]bool
+	// EmptyValueStyle 空值属性的输出样式
+	EmptyValueStyle EmptyValueStyle
+	// MinimalEscaping 属性值只转义 &、< 和双引号，> 和单引号原样输出
+	MinimalEscaping bool
+	// NormalizeClassAttribute 输出 class 属性时按空白拆分，去重并排序后以单个空格连接
+	NormalizeClassAttribute bool
+	// CDATAForMarkupText 含有 <、> 或 & 的文本节点输出为 CDATA 区段而不是转义
+	// 文本中有需要按 OutputEncoding 或 ForceEncodeRunes 编码的字符时仍按普通文本转义
+	CDATAForMarkupText bool
+	// EscapeAttributeWhitespace 将属性值中的 \n、\t、\r 输出为 &#10;、&#9;、&#13;，保证重新解析后值不变
+	EscapeAttributeWhitespace bool
+	// UnquotedAttributes 值安全时不加引号输出的属性名（如 tabindex=1），其余属性始终加引号
+	UnquotedAttributes map[string]bool
+	// MaxLineWidth 开始标签超过该显示宽度时每个属性单独成行（0 表示不限制，紧凑模式下不生效）
+	MaxLineWidth int
+	// TabWidth 计算显示宽度时制表符的宽度（默认：4），仅在 MaxLineWidth > 0 时使用
+	TabWidth int
+	// CompactSmallElements 非紧凑模式下仍将小元素（单个短文本子节点）渲染在一行内，如 <b>x</b>
+	CompactSmallElements bool
+	// GroupConsecutiveVoids 相邻的自闭合 void 元素（如连续的 <br>）渲染在同一行
+	GroupConsecutiveVoids bool
+	// OmitRedundantNamespaces 省略与祖先元素作用域内绑定相同的 xmlns 声明
+	OmitRedundantNamespaces bool
+	// RenderControlAttribute 控制元素子树渲染模式的属性名（如 "data-render"），不会输出到结果中；
+	// 取值 compact 在该子树内使用紧凑模式并丢弃只含空白的文本节点，expanded 使用展开模式，
+	// preserve 使用紧凑模式并原样输出全部文本（需以 TrimWhitespace 为 false 解析才能保留源码空白）
+	RenderControlAttribute string
+	// SlotTag RenderTemplate 识别的插槽元素标签名（默认："slot"），插槽通过 name 属性命名
+	SlotTag string
+	// EstimatedSize 预计的输出字节数，用于预分配缓冲区（0 表示按节点数估算）
+	EstimatedSize int
+	// InitialDepth 顶层节点的起始深度，用于将输出嵌入到已有缩进的父元素中
+	InitialDepth int
+	// Newline 换行符（默认："\n"），可选 "\r\n" 或 "\r"
+	Newline string
+	// OutputEncoding 输出字符编码，目标字符集之外的字符会被转为数字字符引用，XML 声明中的 encoding 随之改写；
+	// 为空时按 UTF-8 输出且不修改声明中的 encoding
+	OutputEncoding string
+	// ForceEncodeRunes 无论输出编码如何，文本和属性值中始终输出为数字字符引用的字符（如零宽空格 U+200B）
+	ForceEncodeRunes []rune
 }
 
 // EmptyElementStyle 空元素样式枚举
@@ -36,6 +97,23 @@ const (
 	PairedTagStyle
 	// VoidElementStyle 基于配置的 void 元素样式
 	VoidElementStyle
+	// HTMLCompatStyle HTML 兼容样式：配置中的 void 元素输出为 <br>，其他空元素一律输出为 <div></div>
+	HTMLCompatStyle
+)
+
+// EmptyValueStyle 空值属性样式枚举
+type EmptyValueStyle int
+
+const (
+	// PreserveEmptyValue 保留源码形式：显式空值输出 key=""，布尔属性输出 key
+	PreserveEmptyValue EmptyValueStyle = iota
+	// BareEmptyValue 空值属性一律输出为 key
+	BareEmptyValue
+	// QuotedEmptyValue 空值属性一律输出为 key=""
+	QuotedEmptyValue
+	// HTML5BooleanValue 属性处理器识别的布尔属性一律输出为 key（忽略其值，如 disabled="disabled"），
+	// 其他空值属性输出为 key=""
+	HTML5BooleanValue
 )
 
 // ValidationOptions 验证选项
@@ -60,11 +138,75 @@ func (e *ValidationError) Error() string {
 		e.Position.Line, e.Position.Column, e.Message)
 }
 
+// ValidationErrors 多个验证错误的集合，可作为单个 error 传递
+// errors.As 可从中取出各个 *ValidationError
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d validation errors:", len(e))
+	for _, err := range e {
+		sb.WriteString("\n  - ")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap 返回各个验证错误，供 errors.Is/errors.As 使用
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// SpanMapping 记录渲染输出中一段字节区间与源节点的对应关系
+type SpanMapping struct {
+	// Start 输出中的起始字节偏移（包含）
+	Start int
+	// End 输出中的结束字节偏移（不包含）
+	End int
+	// Node 产生该输出片段的节点
+	Node Node
+	// Position 节点在源码中的位置
+	Position Position
+}
+
+// countingWriter 统计已写入字节数的 Writer 包装
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
+
 // Renderer 通用标记语言渲染器
 type Renderer struct {
 	options    *RenderOptions
 	config     *ParserConfig
 	validation *ValidationOptions
+
+	// 源码映射记录（仅在 RenderWithSourceMap 期间有效）
+	counter *countingWriter
+	spans   []SpanMapping
+
+	// nsScope 渲染过程中祖先元素已声明的命名空间（仅 OmitRedundantNamespaces 时使用）
+	nsScope map[string]string
+
+	// slots 插槽名称到替换节点的映射（仅在 RenderTemplate 期间有效）
+	slots map[string][]Node
+
+	// compactRegion 是否位于 RenderControlAttribute 指定为 compact 的子树内
+	compactRegion bool
 }
 
 // NewRenderer 创建默认渲染器
@@ -78,6 +220,7 @@ func NewRenderer() *Renderer {
 			SortAttributes:     false,
 			EmptyElementStyle:  SelfClosingStyle,
 			IncludeDeclaration: true,
+			Newline:            "\n",
 		},
 	}
 }
@@ -129,22 +272,36 @@ func (r *Renderer) Render(doc *Document) string {
 
 // RenderToString 渲染文档为字符串
 func (r *Renderer) RenderToString(doc *Document) (string, error) {
+	b, err := r.RenderToBytes(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// RenderToBytes 渲染文档为字节切片，避免额外的字符串拷贝
+func (r *Renderer) RenderToBytes(doc *Document) ([]byte, error) {
 	if doc == nil {
-		return "", fmt.Errorf("document is nil")
+		return nil, fmt.Errorf("document is nil")
 	}
 
-	// 执行验证
-	if r.validation != nil {
-		if err := r.validateDocument(doc); err != nil {
-			return "", err
-		}
+	var buf bytes.Buffer
+	buf.Grow(r.estimateSize(doc))
+	if err := r.RenderToWriter(doc, &buf); err != nil {
+		return nil, err
 	}
+	return buf.Bytes(), nil
+}
 
-	var sb strings.Builder
-	if err := r.RenderToWriter(doc, &sb); err != nil {
-		return "", err
+// estimatedBytesPerNode 未设置 EstimatedSize 时按节点数估算输出大小所用的平均字节数
+const estimatedBytesPerNode = 32
+
+// estimateSize 估算渲染输出的字节数，用于预分配缓冲区
+func (r *Renderer) estimateSize(doc *Document) int {
+	if r.options.EstimatedSize > 0 {
+		return r.options.EstimatedSize
 	}
-	return sb.String(), nil
+	return countNodes(doc) * estimatedBytesPerNode
 }
 
 // RenderToWriter 渲染文档到 Writer
@@ -156,6 +313,10 @@ func (r *Renderer) RenderToWriter(doc *Document, w io.Writer) error {
 		return fmt.Errorf("writer is nil")
 	}
 
+	if err := r.checkOptions(); err != nil {
+		return err
+	}
+
 	// 执行验证
 	if r.validation != nil {
 		if err := r.validateDocument(doc); err != nil {
@@ -163,14 +324,15 @@ func (r *Renderer) RenderToWriter(doc *Document, w io.Writer) error {
 		}
 	}
 
-	// 渲染文档节点
-	for _, child := range doc.Children {
-		if err := r.renderNode(child, w, 0); err != nil {
+	// 文档自身的声明不会输出时补充 XML 声明
+	if r.options.ForceXMLDeclaration && !(r.options.IncludeDeclaration && doc.hasXMLDeclaration()) {
+		if err := r.writeXMLDeclaration(w); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	// 渲染文档节点
+	return r.renderChildren(doc.Children, w, r.options.InitialDepth)
 }
 
 // RenderElement 渲染单个元素为字符串
@@ -194,8 +356,11 @@ func (r *Renderer) RenderElementToWriter(elem *Element, w io.Writer) error {
 	if w == nil {
 		return fmt.Errorf("writer is nil")
 	}
+	if err := r.checkOptions(); err != nil {
+		return err
+	}
 
-	return r.renderNode(elem, w, 0)
+	return r.renderNode(elem, w, r.options.InitialDepth)
 }
 
 // RenderWithValidation 带验证的渲染
@@ -214,16 +379,184 @@ func (r *Renderer) RenderWithValidation(doc *Document, opts *ValidationOptions)
 	return r.RenderToString(doc)
 }
 
+// ValidateAll 按文档顺序验证所有节点而不渲染，返回全部问题组成的 ValidationErrors，没有问题时返回 nil
+// 每个节点最多报告一个问题
+func (r *Renderer) ValidateAll(doc *Document, opts *ValidationOptions) error {
+	if doc == nil {
+		return fmt.Errorf("document is nil")
+	}
+	if opts == nil {
+		return nil
+	}
+
+	oldValidation := r.validation
+	r.validation = opts
+	defer func() {
+		r.validation = oldValidation
+	}()
+
+	var errs ValidationErrors
+	if err, ok := r.validateDeclarationPlacement(doc).(*ValidationError); ok {
+		errs = append(errs, err)
+	}
+	errs = r.collectValidationErrors(doc.Children, errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ValidateDocument 不经过渲染直接验证文档，返回发现的第一个问题，opts 为 nil 时不做任何检查
+func ValidateDocument(doc *Document, opts *ValidationOptions) error {
+	return ValidateWithConfig(doc, nil, opts)
+}
+
+// ValidateWithConfig 与 ValidateDocument 相同，但在指定的解析器配置（如 void 元素集合）下进行验证
+func ValidateWithConfig(doc *Document, config *ParserConfig, opts *ValidationOptions) error {
+	if doc == nil {
+		return fmt.Errorf("document is nil")
+	}
+	r := &Renderer{config: config, validation: opts}
+	return r.validateDocument(doc)
+}
+
+// RenderWithSourceMap 渲染文档并返回输出字节区间到源节点的映射
+// 映射按节点的先序遍历顺序排列
+func (r *Renderer) RenderWithSourceMap(doc *Document) (string, []SpanMapping, error) {
+	if doc == nil {
+		return "", nil, fmt.Errorf("document is nil")
+	}
+
+	var sb strings.Builder
+	r.counter = &countingWriter{w: &sb}
+	r.spans = []SpanMapping{}
+	defer func() {
+		r.counter = nil
+		r.spans = nil
+	}()
+
+	if err := r.RenderToWriter(doc, r.counter); err != nil {
+		return "", nil, err
+	}
+	return sb.String(), r.spans, nil
+}
+
+// RenderTemplate 渲染文档，将插槽元素（如 <slot name="body"/>）替换为 slots 中同名的节点，不修改 AST
+// slots 中没有对应名称的插槽输出插槽元素自身的子节点作为默认内容
+func (r *Renderer) RenderTemplate(doc *Document, slots map[string][]Node) (string, error) {
+	if doc == nil {
+		return "", fmt.Errorf("document is nil")
+	}
+
+	r.slots = slots
+	if r.slots == nil {
+		r.slots = map[string][]Node{}
+	}
+	defer func() {
+		r.slots = nil
+	}()
+
+	if children, expanded := r.expandSlots(doc.Children); expanded {
+		filled := *doc
+		filled.Children = children
+		doc = &filled
+	}
+	return r.RenderToString(doc)
+}
+
+// slotTag 返回插槽元素的标签名
+func (r *Renderer) slotTag() string {
+	if r.options.SlotTag == "" {
+		return "slot"
+	}
+	return r.options.SlotTag
+}
+
+// expandSlots 将子节点列表中的插槽元素替换为对应的节点，expanded 表示是否发生了替换
+func (r *Renderer) expandSlots(children []Node) (result []Node, expanded bool) {
+	for i, child := range children {
+		slot, ok := child.(*Element)
+		if !ok || slot.TagName != r.slotTag() {
+			if expanded {
+				result = append(result, child)
+			}
+			continue
+		}
+
+		if !expanded {
+			result = append(make([]Node, 0, len(children)), children[:i]...)
+			expanded = true
+		}
+		if nodes, ok := r.slots[slot.Attributes["name"]]; ok {
+			result = append(result, nodes...)
+		} else {
+			result = append(result, slot.Children...)
+		}
+	}
+	if !expanded {
+		return children, false
+	}
+	return result, true
+}
+
 // renderNode 渲染单个节点
 func (r *Renderer) renderNode(node Node, w io.Writer, depth int) error {
 	if node == nil {
 		return nil
 	}
 
+	if r.options.NodeFilter != nil {
+		render, replacement := r.options.NodeFilter(node)
+		if !render {
+			return nil
+		}
+		if replacement != nil {
+			node = replacement
+		}
+	}
+
+	// 模板渲染时使用替换了插槽的浅拷贝，不修改原节点
+	if r.slots != nil {
+		switch n := node.(type) {
+		case *Element:
+			if children, expanded := r.expandSlots(n.Children); expanded {
+				filled := *n
+				filled.Children = children
+				node = &filled
+			}
+		case *Fragment:
+			if children, expanded := r.expandSlots(n.Children); expanded {
+				node = &Fragment{Children: children, Pos: n.Pos}
+			}
+		}
+	}
+
+	// 记录源码映射
+	if r.counter != nil {
+		index := len(r.spans)
+		r.spans = append(r.spans, SpanMapping{
+			Start:    r.counter.n,
+			Node:     node,
+			Position: node.Position(),
+		})
+		err := r.renderNodeContent(node, w, depth)
+		r.spans[index].End = r.counter.n
+		return err
+	}
+
+	return r.renderNodeContent(node, w, depth)
+}
+
+// renderNodeContent 根据节点类型分派渲染
+func (r *Renderer) renderNodeContent(node Node, w io.Writer, depth int) error {
 	switch n := node.(type) {
 	case *Document:
 		return r.renderDocument(n, w, depth)
 	case *Element:
+		if mode := r.renderControl(n); mode != "" {
+			return r.renderElementWithMode(n, mode, w, depth)
+		}
 		return r.renderElement(n, w, depth)
 	case *Text:
 		return r.renderText(n, w, depth)
@@ -235,6 +568,9 @@ func (r *Renderer) renderNode(node Node, w io.Writer, depth int) error {
 		return r.renderDoctype(n, w, depth)
 	case *CDATA:
 		return r.renderCDATA(n, w, depth)
+	case *Fragment:
+		// 片段按空标签名的元素输出为 <>...</>
+		return r.renderElement(&Element{TagName: "", Children: n.Children, Pos: n.Pos}, w, depth)
 	default:
 		return fmt.Errorf("unknown node type: %T", node)
 	}
@@ -242,77 +578,87 @@ func (r *Renderer) renderNode(node Node, w io.Writer, depth int) error {
 
 // renderDocument 渲染文档节点
 func (r *Renderer) renderDocument(doc *Document, w io.Writer, depth int) error {
-	for _, child := range doc.Children {
-		if err := r.renderNode(child, w, depth); err != nil {
+	return r.renderChildren(doc.Children, w, depth)
+}
+
+// renderChildren 依次渲染同级节点，按 GroupConsecutiveVoids 将相邻 void 元素合并到一行
+func (r *Renderer) renderChildren(children []Node, w io.Writer, depth int) error {
+	for i := 0; i < len(children); i++ {
+		end := i
+		for end < len(children) && r.isGroupableVoid(children[end]) {
+			end++
+		}
+		if end-i < 2 {
+			if err := r.renderNode(children[i], w, depth); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := r.renderVoidGroup(children[i:end], w, depth); err != nil {
 			return err
 		}
+		i = end - 1
 	}
 	return nil
 }
 
-// renderElement 渲染元素节点
-func (r *Renderer) renderElement(elem *Element, w io.Writer, depth int) error {
-	indent := strings.Repeat(r.options.Indent, depth)
+// isGroupableVoid 判断节点是否为可合并到同一行的 void 元素
+func (r *Renderer) isGroupableVoid(node Node) bool {
+	if !r.options.GroupConsecutiveVoids || r.options.CompactMode {
+		return false
+	}
+	elem, ok := node.(*Element)
+	if !ok || !elem.SelfClose {
+		return false
+	}
+	return r.config == nil || r.config.IsVoidElement(elem.TagName)
+}
 
-	// 如果不是紧凑模式且不是顶层元素，添加缩进
-	if !r.options.CompactMode && depth > 0 {
-		if _, err := w.Write([]byte(indent)); err != nil {
+// renderVoidGroup 在同一行内渲染一组相邻的 void 元素
+func (r *Renderer) renderVoidGroup(group []Node, w io.Writer, depth int) error {
+	if depth > 0 {
+		if err := r.writeIndent(w, depth); err != nil {
 			return err
 		}
 	}
 
-	// 开始标签
-	if _, err := w.Write([]byte("<")); err != nil {
-		return err
+	r.options.CompactMode = true
+	for _, node := range group {
+		if err := r.renderNode(node, w, depth); err != nil {
+			r.options.CompactMode = false
+			return err
+		}
 	}
-	if _, err := w.Write([]byte(elem.TagName)); err != nil {
-		return err
+	r.options.CompactMode = false
+
+	_, err := w.Write([]byte(r.newline()))
+	return err
+}
+
+// renderElement 渲染元素节点
+func (r *Renderer) renderElement(elem *Element, w io.Writer, depth int) error {
+	// 混合内联内容整体单行输出，避免向内联文本注入缩进；开启 CompactSmallElements 时小元素同样单行输出
+	if !r.options.CompactMode && (r.hasInlineContent(elem) || r.isCompactSmall(elem)) {
+		return r.renderInlineElement(elem, w, depth)
 	}
 
-	// 渲染属性
-	if err := r.renderAttributes(elem, w); err != nil {
+	indent := strings.Repeat(r.options.Indent, depth)
+
+	if err := r.renderStartTag(elem, w, depth); err != nil {
 		return err
 	}
 
+	// 属性按外层作用域判断冗余声明，子元素使用包含本元素声明的作用域
+	if r.options.OmitRedundantNamespaces {
+		outer := r.nsScope
+		r.nsScope = elementNamespaceScope(elem, outer)
+		defer func() { r.nsScope = outer }()
+	}
+
 	// 处理自闭合元素
-	if elem.SelfClose {
-		switch r.options.EmptyElementStyle {
-		case SelfClosingStyle:
-			if _, err := w.Write([]byte(" />")); err != nil {
-				return err
-			}
-		case PairedTagStyle:
-			if _, err := w.Write([]byte("></")); err != nil {
-				return err
-			}
-			if _, err := w.Write([]byte(elem.TagName)); err != nil {
-				return err
-			}
-			if _, err := w.Write([]byte(">")); err != nil {
-				return err
-			}
-		case VoidElementStyle:
-			if r.config != nil && r.config.IsVoidElement(elem.TagName) {
-				if _, err := w.Write([]byte(">")); err != nil {
-					return err
-				}
-			} else {
-				if _, err := w.Write([]byte(" />")); err != nil {
-					return err
-				}
-			}
-		default:
-			if _, err := w.Write([]byte(" />")); err != nil {
-				return err
-			}
-		}
-		// 自闭合元素后换行
-		if !r.options.CompactMode {
-			if _, err := w.Write([]byte("\n")); err != nil {
-				return err
-			}
-		}
-		return nil
+	if elem.SelfClose && !(r.options.PairSelfClosingWithChildren && len(elem.Children) > 0) {
+		return r.renderSelfClosingEnd(elem, w)
 	}
 
 	if _, err := w.Write([]byte(">")); err != nil {
@@ -333,40 +679,19 @@ func (r *Renderer) renderElement(elem *Element, w io.Writer, depth int) error {
 		// 检查是否只有一个文本子节点
 		isSingleTextChild := len(elem.Children) == 1
 		if textChild, ok := elem.Children[0].(*Text); ok && isSingleTextChild {
-			// 单个文本子节点的情况
-			// 对于单行简单文本，添加换行和缩进
-			if !r.options.CompactMode && !strings.ContainsAny(textChild.Content, "\n\r") {
-				if _, err := w.Write([]byte("\n")); err != nil {
-					return err
-				}
-				if _, err := w.Write([]byte(strings.Repeat(r.options.Indent, depth+1))); err != nil {
-					return err
-				}
-			}
-			if err := r.renderText(textChild, w, depth+1); err != nil {
+			if err := r.renderSingleTextChild(textChild, w, depth); err != nil {
 				return err
 			}
-			// 单个文本子节点后也需要换行和缩进
-			if !r.options.CompactMode && !strings.ContainsAny(textChild.Content, "\n\r") {
-				if _, err := w.Write([]byte("\n")); err != nil {
-					return err
-				}
-				if _, err := w.Write([]byte(indent)); err != nil {
-					return err
-				}
-			}
 		} else {
 			// 多个子节点或包含非文本节点的情况
 			if !r.options.CompactMode {
-				if _, err := w.Write([]byte("\n")); err != nil {
+				if _, err := w.Write([]byte(r.newline())); err != nil {
 					return err
 				}
 			}
 
-			for _, child := range elem.Children {
-				if err := r.renderNode(child, w, depth+1); err != nil {
-					return err
-				}
+			if err := r.renderChildren(elem.Children, w, depth+1); err != nil {
+				return err
 			}
 
 			// 结束标签前的缩进（只有在有非文本子节点时）
@@ -378,58 +703,310 @@ func (r *Renderer) renderElement(elem *Element, w io.Writer, depth int) error {
 		}
 	}
 
-	// 结束标签
-	if _, err := w.Write([]byte("</")); err != nil {
-		return err
+	return r.renderEndTag(elem, w)
+}
+
+// renderSingleTextChild 输出深度为 depth 的元素唯一的文本子节点，单行文本另起一行并缩进
+func (r *Renderer) renderSingleTextChild(text *Text, w io.Writer, depth int) error {
+	wrap := !r.options.CompactMode && !strings.ContainsAny(text.Content, "\n\r")
+	if wrap {
+		if _, err := w.Write([]byte(r.newline() + strings.Repeat(r.options.Indent, depth+1))); err != nil {
+			return err
+		}
+	}
+	if err := r.renderNode(text, w, depth+1); err != nil {
+		return err
+	}
+	if wrap {
+		if _, err := w.Write([]byte(r.newline() + strings.Repeat(r.options.Indent, depth))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderStartTag 输出开始标签的缩进、标签名和属性，不含结尾的 > 或 />
+func (r *Renderer) renderStartTag(elem *Element, w io.Writer, depth int) error {
+	// 如果不是紧凑模式且不是顶层元素，添加缩进
+	if !r.options.CompactMode && depth > 0 {
+		if err := r.writeIndent(w, depth); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.Write([]byte("<")); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(elem.TagName)); err != nil {
+		return err
+	}
+
+	// 渲染属性，开始标签过宽时每个属性单独成行
+	if r.shouldWrapAttributes(elem, depth) {
+		return r.renderAttributesWithSeparator(elem, w, r.newline()+strings.Repeat(r.options.Indent, depth+1))
+	}
+	return r.renderAttributes(elem, w)
+}
+
+// renderSelfClosingEnd 按 EmptyElementStyle 输出自闭合元素开始标签之后的部分
+func (r *Renderer) renderSelfClosingEnd(elem *Element, w io.Writer) error {
+	switch r.options.EmptyElementStyle {
+
+	case SelfClosingStyle:
+		if _, err := w.Write([]byte(" />")); err != nil {
+			return err
+		}
+	case PairedTagStyle:
+		if _, err := w.Write([]byte("></")); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(elem.TagName)); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(">")); err != nil {
+			return err
+		}
+	case VoidElementStyle:
+		if r.config != nil && r.config.IsVoidElement(elem.TagName) {
+			if _, err := w.Write([]byte(">")); err != nil {
+				return err
+			}
+		} else {
+			if _, err := w.Write([]byte(" />")); err != nil {
+				return err
+			}
+		}
+	case HTMLCompatStyle:
+		closing := "></" + elem.TagName + ">"
+		if r.config != nil && r.config.IsVoidElement(elem.TagName) {
+			closing = ">"
+		}
+		if _, err := w.Write([]byte(closing)); err != nil {
+			return err
+		}
+	default:
+		if _, err := w.Write([]byte(" />")); err != nil {
+			return err
+		}
+	}
+	// 自闭合元素后换行
+	if !r.options.CompactMode {
+		if _, err := w.Write([]byte(r.newline())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderEndTag 输出结束标签，非紧凑模式下随后换行
+func (r *Renderer) renderEndTag(elem *Element, w io.Writer) error {
+	if _, err := w.Write([]byte("</" + elem.TagName + ">")); err != nil {
+		return err
+	}
+	if !r.options.CompactMode {
+		if _, err := w.Write([]byte(r.newline())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasInlineContent 判断元素是否包含内联元素且子节点全部为文本或内联元素
+func (r *Renderer) hasInlineContent(elem *Element) bool {
+	if len(r.options.InlineElements) == 0 {
+		return false
+	}
+
+	hasInlineChild := false
+	for _, child := range elem.Children {
+		switch c := child.(type) {
+		case *Text:
+		case *Element:
+			if !r.options.InlineElements[c.TagName] || !r.isInlineSubtree(c) {
+				return false
+			}
+			hasInlineChild = true
+		default:
+			return false
+		}
+	}
+	return hasInlineChild
+}
+
+// isInlineSubtree 判断元素的子树是否只包含文本和内联元素
+func (r *Renderer) isInlineSubtree(elem *Element) bool {
+	for _, child := range elem.Children {
+		switch c := child.(type) {
+		case *Text:
+		case *Element:
+			if !r.options.InlineElements[c.TagName] || !r.isInlineSubtree(c) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// renderControl 返回元素 RenderControlAttribute 属性指定的渲染模式，未指定或无法识别时返回空字符串
+func (r *Renderer) renderControl(elem *Element) string {
+	if r.options.RenderControlAttribute == "" {
+		return ""
+	}
+	switch mode := elem.Attributes[r.options.RenderControlAttribute]; mode {
+	case "compact", "expanded", "preserve":
+		return mode
+	default:
+		return ""
+	}
+}
+
+// renderElementWithMode 按元素指定的模式渲染其子树，渲染结束后恢复全局模式
+func (r *Renderer) renderElementWithMode(elem *Element, mode string, w io.Writer, depth int) error {
+	compact, preserve, region := r.options.CompactMode, r.options.PreserveSpace, r.compactRegion
+	defer func() {
+		r.options.CompactMode, r.options.PreserveSpace, r.compactRegion = compact, preserve, region
+	}()
+
+	r.compactRegion = mode == "compact"
+	if mode == "expanded" {
+		r.options.CompactMode = false
+		if compact {
+			// 紧凑的父元素中没有所在行的缩进，子树从第 0 层开始缩进
+			depth = 0
+		}
+		return r.renderElement(elem, w, depth)
+	}
+
+	r.options.PreserveSpace = mode == "preserve"
+	if compact {
+		return r.renderElement(elem, w, depth)
+	}
+
+	// 展开的父元素中按普通子元素的方式缩进和换行
+	if depth > 0 {
+		if err := r.writeIndent(w, depth); err != nil {
+			return err
+		}
+	}
+	r.options.CompactMode = true
+	if err := r.renderElement(elem, w, depth); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte(r.newline()))
+	return err
+}
+
+// renderInlineElement 以紧凑形式单行渲染元素，保留缩进和换行
+func (r *Renderer) renderInlineElement(elem *Element, w io.Writer, depth int) error {
+	if depth > 0 {
+		if err := r.writeIndent(w, depth); err != nil {
+			return err
+		}
+	}
+
+	r.options.CompactMode = true
+	err := r.renderElement(elem, w, depth)
+	r.options.CompactMode = false
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write([]byte(r.newline()))
+	return err
+}
+
+// shouldWrapAttributes 判断开始标签的显示宽度是否超过 MaxLineWidth
+func (r *Renderer) shouldWrapAttributes(elem *Element, depth int) bool {
+	if r.options.MaxLineWidth <= 0 || r.options.CompactMode || len(elem.Attributes) == 0 {
+		return false
+	}
+
+	var attrs bytes.Buffer
+	if err := r.renderAttributes(elem, &attrs); err != nil {
+		return false
 	}
-	if _, err := w.Write([]byte(elem.TagName)); err != nil {
-		return err
+
+	line := "<" + elem.TagName + attrs.String() + ">"
+	if elem.SelfClose {
+		line = "<" + elem.TagName + attrs.String() + " />"
 	}
-	if _, err := w.Write([]byte(">")); err != nil {
-		return err
+	if depth > 0 {
+		line = strings.Repeat(r.options.Indent, depth) + line
 	}
+	return r.displayWidth(line) > r.options.MaxLineWidth
+}
 
-	// 元素后换行
-	if !r.options.CompactMode {
-		if _, err := w.Write([]byte("\n")); err != nil {
-			return err
-		}
+// displayWidth 计算字符串的显示宽度，制表符推进到下一个 TabWidth 的倍数
+func (r *Renderer) displayWidth(s string) int {
+	tabWidth := r.options.TabWidth
+	if tabWidth <= 0 {
+		tabWidth = 4
 	}
 
-	return nil
+	width := 0
+	for _, c := range s {
+		if c == '\t' {
+			width += tabWidth - width%tabWidth
+		} else {
+			width++
+		}
+	}
+	return width
 }
 
 // renderAttributes 渲染属性
 func (r *Renderer) renderAttributes(elem *Element, w io.Writer) error {
+	return r.renderAttributesWithSeparator(elem, w, " ")
+}
+
+// renderAttributesWithSeparator 渲染属性，每个属性前写入 sep
+func (r *Renderer) renderAttributesWithSeparator(elem *Element, w io.Writer, sep string) error {
 	if elem.Attributes == nil || len(elem.Attributes) == 0 {
 		return nil
 	}
 
 	// 获取属性键并排序（如果需要）
-	keys := make([]string, 0, len(elem.Attributes))
-	for key := range elem.Attributes {
-		keys = append(keys, key)
-	}
-
-	if r.options.SortAttributes {
-		sort.Strings(keys)
-	}
+	keys := attributeKeys(elem, r.options.SortAttributes)
 
 	// 渲染属性
 	for _, key := range keys {
 		value := elem.Attributes[key]
-		if _, err := w.Write([]byte(" ")); err != nil {
+		if r.isRedundantNamespace(key, value) || key == r.options.RenderControlAttribute {
+			continue
+		}
+		if r.options.NormalizeClassAttribute && key == "class" {
+			value = normalizeClassTokens(value)
+		}
+		if _, err := w.Write([]byte(sep)); err != nil {
 			return err
 		}
 		if _, err := w.Write([]byte(key)); err != nil {
 			return err
 		}
+		if r.options.EmptyValueStyle == HTML5BooleanValue && r.isBooleanAttribute(key) {
+			continue
+		}
 
 		if value != "" {
 			escapedValue := value
 			if r.options.EscapeText {
-				escapedValue = escapeText(value)
+				escapedValue = r.escapeAttribute(value)
+			}
+			if r.options.EscapeAttributeWhitespace {
+				escapedValue = attributeWhitespaceReplacer.Replace(escapedValue)
+			}
+			escapedValue = r.encodeOutput(escapedValue)
+
+			// 允许不加引号且值安全的属性直接输出
+			if r.options.UnquotedAttributes[key] && isSafeUnquotedValue(escapedValue) {
+				if _, err := w.Write([]byte("=" + escapedValue)); err != nil {
+					return err
+				}
+				continue
 			}
+
 			if _, err := w.Write([]byte(`="`)); err != nil {
 				return err
 			}
@@ -439,26 +1016,154 @@ func (r *Renderer) renderAttributes(elem *Element, w io.Writer) error {
 			if _, err := w.Write([]byte(`"`)); err != nil {
 				return err
 			}
+		} else if r.renderEmptyValue(elem, key) {
+			if _, err := w.Write([]byte(`=""`)); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// normalizeClassTokens 拆分 class 值，去除重复的类名并排序后以单个空格连接
+func normalizeClassTokens(value string) string {
+	tokens := strings.Fields(value)
+	if len(tokens) < 2 {
+		return strings.Join(tokens, " ")
+	}
+	sort.Strings(tokens)
+	unique := tokens[:1]
+	for _, token := range tokens[1:] {
+		if token != unique[len(unique)-1] {
+			unique = append(unique, token)
+		}
+	}
+	return strings.Join(unique, " ")
+}
+
+// attributeWhitespaceReplacer 将属性值中的换行、制表符和回车转为字符引用，避免被属性值规范化为空格
+var attributeWhitespaceReplacer = strings.NewReplacer("\n", "&#10;", "\t", "&#9;", "\r", "&#13;")
+
+// isRedundantNamespace 判断属性是否为作用域内已有相同绑定的命名空间声明
+func (r *Renderer) isRedundantNamespace(key, value string) bool {
+	if !r.options.OmitRedundantNamespaces {
+		return false
+	}
+	prefix, ok := namespaceDeclPrefix(key)
+	if !ok {
+		return false
+	}
+	uri, inScope := r.nsScope[prefix]
+	return inScope && uri == value
+}
+
+// elementNamespaceScope 返回在 outer 基础上叠加元素命名空间声明后的作用域，无新声明时复用 outer
+func elementNamespaceScope(elem *Element, outer map[string]string) map[string]string {
+	scope := outer
+	copied := false
+	for key, value := range elem.Attributes {
+		prefix, ok := namespaceDeclPrefix(key)
+		if !ok {
+			continue
+		}
+		if uri, inScope := outer[prefix]; inScope && uri == value {
+			continue
+		}
+
+		if !copied {
+			scope = make(map[string]string, len(outer)+1)
+			for k, v := range outer {
+				scope[k] = v
+			}
+			copied = true
+		}
+		scope[prefix] = value
+	}
+	return scope
+}
+
+// renderEmptyValue 判断空值属性是否输出为 key=""
+func (r *Renderer) renderEmptyValue(elem *Element, key string) bool {
+	switch r.options.EmptyValueStyle {
+	case BareEmptyValue:
+		return false
+	case QuotedEmptyValue, HTML5BooleanValue:
+		return true
+	default:
+		return elem.EmptyAttributes[key]
+	}
+}
+
+// isBooleanAttribute 使用配置的属性处理器判断属性是否为布尔属性
+func (r *Renderer) isBooleanAttribute(key string) bool {
+	if r.config == nil || r.config.AttributeProcessor == nil {
+		return (&DefaultAttributeProcessor{}).IsBooleanAttribute(key)
+	}
+	return r.config.AttributeProcessor.IsBooleanAttribute(r.config.NormalizeCase(key))
+}
+
+// attributeKeys 返回元素属性的输出顺序
+// 不排序时优先按 AttributeOrder 输出，未记录顺序的属性按名称排序追加在后
+func attributeKeys(elem *Element, sortKeys bool) []string {
+	keys := make([]string, 0, len(elem.Attributes))
+	if sortKeys {
+		for key := range elem.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	seen := make(map[string]bool, len(elem.AttributeOrder))
+	for _, key := range elem.AttributeOrder {
+		if _, ok := elem.Attributes[key]; ok && !seen[key] {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+
+	var rest []string
+	for key := range elem.Attributes {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
 // renderText 渲染文本节点
 func (r *Renderer) renderText(text *Text, w io.Writer, depth int) error {
+	// compact 子树内只含空白的文本节点视为排版空白，preserve 子树内原样保留
+	if r.compactRegion && !r.options.PreserveSpace && strings.TrimSpace(text.Content) == "" {
+		return nil
+	}
 	content := text.Content
+	if r.options.TextTransform != nil {
+		content = r.options.TextTransform(content)
+	}
+	// 含有标记字符的文本原样包裹在 CDATA 区段中，不做转义和缩进处理；
+	// CDATA 中无法使用字符引用，含有需要按输出编码转义的字符时仍按普通文本输出
+	if r.options.CDATAForMarkupText && strings.ContainsAny(content, "<>&") && r.encodeOutput(content) == content {
+		_, err := w.Write([]byte(cdataSection(content)))
+		return err
+	}
 	if r.options.EscapeText {
-		content = escapeText(content)
+		content = r.escape(content)
 	}
+	content = r.encodeOutput(content)
 
 	// 如果不是紧凑模式，并且文本包含换行或者是多行文本，需要处理缩进
 	if !r.options.CompactMode && strings.ContainsAny(content, "\n\r\t") {
 		// 对于包含换行的文本，保持原有格式但添加适当的缩进
+		if r.newline() != "\n" {
+			content = normalizeLineEndings(content)
+		}
 		lines := strings.Split(content, "\n")
 		for i, line := range lines {
 			if i > 0 {
-				if _, err := w.Write([]byte("\n")); err != nil {
+				if _, err := w.Write([]byte(r.newline())); err != nil {
 					return err
 				}
 				if strings.TrimSpace(line) != "" { // 只对非空行添加缩进
@@ -493,7 +1198,7 @@ func (r *Renderer) renderComment(comment *Comment, w io.Writer, depth int) error
 	}
 
 	if !r.options.CompactMode {
-		if _, err := w.Write([]byte("\n")); err != nil {
+		if _, err := w.Write([]byte(r.newline())); err != nil {
 			return err
 		}
 	}
@@ -518,8 +1223,16 @@ func (r *Renderer) renderProcessingInstruction(pi *ProcessingInstruction, w io.W
 		return err
 	}
 
-	if pi.Content != "" {
-		if _, err := w.Write([]byte(" " + pi.Content)); err != nil {
+	content := pi.Content
+	if len(pi.PseudoAttributes) > 0 {
+		content = renderPseudoAttributes(pi.PseudoAttributes)
+	}
+	if pi.Target == "xml" {
+		content = r.declareEncoding(content)
+	}
+
+	if content != "" {
+		if _, err := w.Write([]byte(" " + content)); err != nil {
 			return err
 		}
 	}
@@ -529,7 +1242,7 @@ func (r *Renderer) renderProcessingInstruction(pi *ProcessingInstruction, w io.W
 	}
 
 	if !r.options.CompactMode {
-		if _, err := w.Write([]byte("\n")); err != nil {
+		if _, err := w.Write([]byte(r.newline())); err != nil {
 			return err
 		}
 	}
@@ -537,6 +1250,45 @@ func (r *Renderer) renderProcessingInstruction(pi *ProcessingInstruction, w io.W
 	return nil
 }
 
+// writeXMLDeclaration 输出由 DeclarationVersion 和 DeclarationEncoding 构成的 XML 声明
+func (r *Renderer) writeXMLDeclaration(w io.Writer) error {
+	version := r.options.DeclarationVersion
+	if version == "" {
+		version = "1.0"
+	}
+	encoding := r.options.DeclarationEncoding
+	if encoding == "" {
+		encoding = "UTF-8"
+	}
+
+	content := r.declareEncoding(`version="` + version + `" encoding="` + encoding + `"`)
+	if _, err := w.Write([]byte("<?xml " + content + "?>")); err != nil {
+		return err
+	}
+
+	if !r.options.CompactMode {
+		if _, err := w.Write([]byte(r.newline())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderPseudoAttributes 按名称排序重建处理指令的伪属性内容
+func renderPseudoAttributes(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+`="`+strings.ReplaceAll(attrs[key], `"`, "&quot;")+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
 // renderDoctype 渲染 DOCTYPE 节点
 func (r *Renderer) renderDoctype(doctype *Doctype, w io.Writer, depth int) error {
 	// 如果不包含声明，跳过 DOCTYPE
@@ -550,12 +1302,12 @@ func (r *Renderer) renderDoctype(doctype *Doctype, w io.Writer, depth int) error
 		}
 	}
 
-	if _, err := w.Write([]byte("<!DOCTYPE " + doctype.Content + ">")); err != nil {
+	if _, err := w.Write([]byte("<!DOCTYPE " + doctype.declaration() + ">")); err != nil {
 		return err
 	}
 
 	if !r.options.CompactMode {
-		if _, err := w.Write([]byte("\n")); err != nil {
+		if _, err := w.Write([]byte(r.newline())); err != nil {
 			return err
 		}
 	}
@@ -563,6 +1315,11 @@ func (r *Renderer) renderDoctype(doctype *Doctype, w io.Writer, depth int) error
 	return nil
 }
 
+// cdataSection 将内容包裹为 CDATA 区段，内容中的 "]]>" 会拆分到相邻的两个区段中
+func cdataSection(content string) string {
+	return "<![CDATA[" + strings.ReplaceAll(content, "]]>", "]]]]><![CDATA[>") + "]]>"
+}
+
 // renderCDATA 渲染 CDATA 节点
 func (r *Renderer) renderCDATA(cdata *CDATA, w io.Writer, depth int) error {
 	if !r.options.CompactMode && depth > 0 {
@@ -571,12 +1328,12 @@ func (r *Renderer) renderCDATA(cdata *CDATA, w io.Writer, depth int) error {
 		}
 	}
 
-	if _, err := w.Write([]byte("<![CDATA[" + cdata.Content + "]]>")); err != nil {
+	if _, err := w.Write([]byte(cdataSection(cdata.Content))); err != nil {
 		return err
 	}
 
 	if !r.options.CompactMode {
-		if _, err := w.Write([]byte("\n")); err != nil {
+		if _, err := w.Write([]byte(r.newline())); err != nil {
 			return err
 		}
 	}
@@ -584,6 +1341,26 @@ func (r *Renderer) renderCDATA(cdata *CDATA, w io.Writer, depth int) error {
 	return nil
 }
 
+// newline 返回配置的换行符
+func (r *Renderer) newline() string {
+	if r.options.Newline == "" {
+		return "\n"
+	}
+	return r.options.Newline
+}
+
+// checkOptions 检查渲染选项是否合法
+func (r *Renderer) checkOptions() error {
+	switch r.options.Newline {
+	case "", "\n", "\r\n", "\r":
+	default:
+		return fmt.Errorf("unsupported newline: %q", r.options.Newline)
+	}
+
+	_, err := r.outputMaxRune()
+	return err
+}
+
 // writeIndent 写入缩进
 func (r *Renderer) writeIndent(w io.Writer, depth int) error {
 	for i := 0; i < depth; i++ {
@@ -594,6 +1371,11 @@ func (r *Renderer) writeIndent(w io.Writer, depth int) error {
 	return nil
 }
 
+// isCompactSmall 判断非空元素是否按 CompactSmallElements 单行输出
+func (r *Renderer) isCompactSmall(elem *Element) bool {
+	return r.options.CompactSmallElements && len(elem.Children) > 0 && r.isSmallElement(elem)
+}
+
 // isSmallElement 判断是否为小元素（适合紧凑模式）
 func (r *Renderer) isSmallElement(elem *Element) bool {
 	if len(elem.Children) == 0 {
@@ -627,6 +1409,10 @@ func (r *Renderer) validateDocument(doc *Document) error {
 
 	var errors []error
 
+	if err := r.validateDeclarationPlacement(doc); err != nil {
+		errors = append(errors, err)
+	}
+
 	// 遍历文档检查各种验证规则
 	for _, child := range doc.Children {
 		if err := r.validateNode(child); err != nil {
@@ -652,13 +1438,90 @@ func (r *Renderer) validateNode(node Node) error {
 		return r.validateElement(n)
 	case *Text:
 		return r.validateText(n)
+	case *Fragment:
+		for _, child := range n.Children {
+			if err := r.validateNode(child); err != nil {
+				return err
+			}
+		}
+		return nil
 	default:
 		return nil
 	}
 }
 
-// validateElement 验证元素节点
+// collectValidationErrors 按文档顺序逐个验证节点，将发现的问题追加到 errs
+func (r *Renderer) collectValidationErrors(nodes []Node, errs ValidationErrors) ValidationErrors {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Element:
+			if err, ok := r.checkElement(n).(*ValidationError); ok {
+				errs = append(errs, err)
+			}
+			errs = r.collectValidationErrors(n.Children, errs)
+		case *Fragment:
+			errs = r.collectValidationErrors(n.Children, errs)
+		case *Text:
+			if err, ok := r.validateText(n).(*ValidationError); ok {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// validateDeclarationPlacement 检查 XML 声明是否只出现在文档的第一个节点
+func (r *Renderer) validateDeclarationPlacement(doc *Document) error {
+	if !r.validation.CheckWellFormed {
+		return nil
+	}
+
+	misplaced := findMisplacedDeclaration(doc.Children, true)
+	if misplaced != nil {
+		return &ValidationError{
+			Message:  "XML declaration allowed only at the start of the document",
+			Position: misplaced.Position(),
+			NodeType: NodeTypeProcessingInstruction,
+		}
+	}
+	return nil
+}
+
+// findMisplacedDeclaration 按文档顺序查找不在文档开头的 XML 声明
+func findMisplacedDeclaration(nodes []Node, top bool) *ProcessingInstruction {
+	for i, node := range nodes {
+		switch n := node.(type) {
+		case *ProcessingInstruction:
+			if isXMLDeclaration(n) && !(top && i == 0) {
+				return n
+			}
+		case *Element:
+			if pi := findMisplacedDeclaration(n.Children, false); pi != nil {
+				return pi
+			}
+		}
+	}
+	return nil
+}
+
+// validateElement 验证元素节点及其子树
 func (r *Renderer) validateElement(elem *Element) error {
+	if err := r.checkElement(elem); err != nil {
+		return err
+	}
+
+	// 递归验证子节点
+	for _, child := range elem.Children {
+		if err := r.validateNode(child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkElement 验证元素自身（不含子节点），返回发现的第一个问题
+func (r *Renderer) checkElement(elem *Element) error {
 	if r.validation.CheckWellFormed {
 		// 检查标签名是否有效
 		if !isValidTagName(elem.TagName) {
@@ -669,6 +1532,15 @@ func (r *Renderer) validateElement(elem *Element) error {
 			}
 		}
 
+		// 自闭合元素不能包含子节点
+		if elem.SelfClose && len(elem.Children) > 0 {
+			return &ValidationError{
+				Message:  fmt.Sprintf("self-closing element <%s> has children", elem.TagName),
+				Position: elem.Position(),
+				NodeType: NodeTypeElement,
+			}
+		}
+
 		// 检查属性名是否有效
 		for attrName := range elem.Attributes {
 			if !isValidAttributeName(attrName) {
@@ -679,12 +1551,16 @@ func (r *Renderer) validateElement(elem *Element) error {
 				}
 			}
 		}
-	}
 
-	// 递归验证子节点
-	for _, child := range elem.Children {
-		if err := r.validateNode(child); err != nil {
-			return err
+		// 检查属性值中是否存在未转义的 '&'
+		for attrName, attrValue := range elem.Attributes {
+			if !elem.DecodedAttributes[attrName] && hasRawAmpersand(attrValue) {
+				return &ValidationError{
+					Message:  fmt.Sprintf("unescaped '&' in value of attribute: %s", attrName),
+					Position: elem.Position(),
+					NodeType: NodeTypeElement,
+				}
+			}
 		}
 	}
 
@@ -737,6 +1613,211 @@ func isValidAttributeName(name string) bool {
 	return isValidTagName(name) // 使用相同的规则
 }
 
+// hasRawAmpersand 检查字符串中是否存在不构成合法实体引用的 '&'
+// 合法形式为 &name;、&#123; 和 &#x7B;
+func hasRawAmpersand(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '&' {
+			continue
+		}
+		end := strings.IndexByte(s[i+1:], ';')
+		if end <= 0 || !isValidEntityName(s[i+1:i+1+end]) {
+			return true
+		}
+		i += end + 1
+	}
+	return false
+}
+
+// isValidEntityName 检查实体引用中 '&' 与 ';' 之间的部分是否合法
+func isValidEntityName(name string) bool {
+	if strings.HasPrefix(name, "#x") || strings.HasPrefix(name, "#X") {
+		digits := name[2:]
+		if digits == "" {
+			return false
+		}
+		for _, r := range digits {
+			if !((r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')) {
+				return false
+			}
+		}
+		return true
+	}
+	if strings.HasPrefix(name, "#") {
+		digits := name[1:]
+		if digits == "" {
+			return false
+		}
+		for _, r := range digits {
+			if r < '0' || r > '9' {
+				return false
+			}
+		}
+		return true
+	}
+	return isValidTagName(name)
+}
+
+// xmlEncodingPattern 匹配 XML 声明中的 encoding 伪属性
+var xmlEncodingPattern = regexp.MustCompile(`encoding\s*=\s*("[^"]*"|'[^']*')`)
+
+// outputMaxRune 返回输出编码可直接表示的最大字符
+func (r *Renderer) outputMaxRune() (rune, error) {
+	switch strings.ToLower(r.options.OutputEncoding) {
+	case "", "utf-8", "utf8":
+		return utf8.MaxRune, nil
+	case "ascii", "us-ascii":
+		return 0x7F, nil
+	case "iso-8859-1", "latin1":
+		return 0xFF, nil
+	default:
+		return 0, fmt.Errorf("unsupported output encoding: %s", r.options.OutputEncoding)
+	}
+}
+
+// encodeOutput 将输出编码无法表示的字符转为数字字符引用
+func (r *Renderer) encodeOutput(s string) string {
+	maxRune, err := r.outputMaxRune()
+	if err != nil {
+		maxRune = utf8.MaxRune
+	}
+	if maxRune == utf8.MaxRune && len(r.options.ForceEncodeRunes) == 0 {
+		return s
+	}
+
+	mustEncode := func(c rune) bool {
+		return c > maxRune || containsRune(r.options.ForceEncodeRunes, c)
+	}
+	if strings.IndexFunc(s, mustEncode) < 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	for _, c := range s {
+		if mustEncode(c) {
+			sb.WriteString(fmt.Sprintf("&#%d;", c))
+		} else {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// containsRune 检查切片中是否包含字符 c
+func containsRune(runes []rune, c rune) bool {
+	for _, r := range runes {
+		if r == c {
+			return true
+		}
+	}
+	return false
+}
+
+// declareEncoding 让 XML 声明中的 encoding 与输出编码保持一致
+func (r *Renderer) declareEncoding(content string) string {
+	encoding := r.options.OutputEncoding
+	if encoding == "" {
+		return content
+	}
+
+	declared := `encoding="` + encoding + `"`
+	if match := xmlEncodingPattern.FindStringSubmatch(content); match != nil {
+		current := match[1][1 : len(match[1])-1]
+		if strings.EqualFold(current, encoding) {
+			return content
+		}
+		return xmlEncodingPattern.ReplaceAllLiteralString(content, declared)
+	}
+
+	// 未声明 encoding 时默认为 UTF-8，无需补充
+	if strings.EqualFold(strings.ReplaceAll(encoding, "-", ""), "utf8") {
+		return content
+	}
+	if content == "" {
+		return declared
+	}
+	return content + " " + declared
+}
+
+// isSafeUnquotedValue 检查属性值是否可以不加引号输出
+func isSafeUnquotedValue(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if unicode.IsSpace(r) || strings.ContainsRune("\"'=<>`", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// escape 根据 AssumePreEscaped 选项转义文本或属性值
+func (r *Renderer) escape(s string) string {
+	if r.options.AssumePreEscaped {
+		return escapePreEscapedText(s)
+	}
+	return escapeText(s)
+}
+
+// escapeAttribute 转义属性值，MinimalEscaping 时只转义 XML 要求的 &、< 和引号 "
+func (r *Renderer) escapeAttribute(s string) string {
+	if !r.options.MinimalEscaping {
+		return r.escape(s)
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '&':
+			end := strings.IndexByte(s[i+1:], ';')
+			if r.options.AssumePreEscaped && end > 0 && isValidEntityName(s[i+1:i+1+end]) {
+				sb.WriteString(s[i : i+end+2])
+				i += end + 1
+			} else {
+				sb.WriteString("&amp;")
+			}
+		case '<':
+			sb.WriteString("&lt;")
+		case '"':
+			sb.WriteString("&quot;")
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// escapePreEscapedText 转义文本内容，但保留已构成合法实体引用的 '&'
+func escapePreEscapedText(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '&':
+			end := strings.IndexByte(s[i+1:], ';')
+			if end > 0 && isValidEntityName(s[i+1:i+1+end]) {
+				sb.WriteString(s[i : i+end+2])
+				i += end + 1
+			} else {
+				sb.WriteString("&amp;")
+			}
+		case '<':
+			sb.WriteString("&lt;")
+		case '>':
+			sb.WriteString("&gt;")
+		case '"':
+			sb.WriteString("&quot;")
+		case '\'':
+			sb.WriteString("&#39;")
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
 // escapeText 转义文本内容
 func escapeText(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
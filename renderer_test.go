@@ -2,6 +2,7 @@ package markit
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -2535,3 +2536,1575 @@ func TestWriteIndentComprehensive(t *testing.T) {
 		}
 	})
 }
+
+// TestRenderWithSourceMap 测试源码映射生成
+func TestRenderWithSourceMap(t *testing.T) {
+	t.Run("element spans point at source nodes", func(t *testing.T) {
+		input := "<root>\n  <a>one</a>\n  <b id=\"x\" />\n</root>"
+		doc, err := NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+
+		renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true})
+		output, mapping, err := renderer.RenderWithSourceMap(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+
+		expected, _ := renderer.RenderToString(doc)
+		if output != expected {
+			t.Errorf("output mismatch: expected %q, got %q", expected, output)
+		}
+
+		root := doc.Children[0].(*Element)
+		a := root.Children[0].(*Element)
+		b := root.Children[1].(*Element)
+
+		spans := map[Node]string{}
+		for _, m := range mapping {
+			if m.Position != m.Node.Position() {
+				t.Errorf("position mismatch for %s: %v vs %v", m.Node, m.Position, m.Node.Position())
+			}
+			spans[m.Node] = output[m.Start:m.End]
+		}
+
+		if spans[root] != output {
+			t.Errorf("root span should cover whole output, got %q", spans[root])
+		}
+		if spans[a] != "<a>one</a>" {
+			t.Errorf("unexpected span for <a>: %q", spans[a])
+		}
+		if spans[b] != `<b id="x" />` {
+			t.Errorf("unexpected span for <b>: %q", spans[b])
+		}
+		if spans[a.Children[0]] != "one" {
+			t.Errorf("unexpected span for text: %q", spans[a.Children[0]])
+		}
+		if a.Position().Line != 2 || b.Position().Line != 3 {
+			t.Errorf("unexpected source lines: %v, %v", a.Position(), b.Position())
+		}
+	})
+
+	t.Run("mapping is in document order", func(t *testing.T) {
+		doc, _ := NewParser("<r><x/><y/></r>").Parse()
+		_, mapping, err := NewRenderer().RenderWithSourceMap(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+
+		var names []string
+		for _, m := range mapping {
+			names = append(names, m.Node.String())
+		}
+		if strings.Join(names, ",") != "r,x,y" {
+			t.Errorf("unexpected mapping order: %v", names)
+		}
+		for _, m := range mapping {
+			if m.Start > m.End {
+				t.Errorf("invalid span %d-%d for %s", m.Start, m.End, m.Node)
+			}
+		}
+	})
+
+	t.Run("nil document", func(t *testing.T) {
+		_, _, err := NewRenderer().RenderWithSourceMap(nil)
+		if err == nil {
+			t.Error("expected error for nil document")
+		}
+	})
+}
+
+// TestValidationRawAmpersand 测试属性值中未转义 '&' 的检测
+func TestValidationRawAmpersand(t *testing.T) {
+	opts := &ValidationOptions{CheckWellFormed: true}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"raw ampersand", `<p title="a & b">x</p>`, true},
+		{"unquoted raw ampersand", `<a href=a&b>x</a>`, true},
+		{"named entity", `<p title="a &amp; b">x</p>`, false},
+		{"decimal reference", `<p title="&#38;">x</p>`, false},
+		{"hex reference", `<p title="&#x26;">x</p>`, false},
+		{"bad numeric reference", `<p title="&#x;">x</p>`, true},
+		{"nested element", `<div><p title="&">x</p></div>`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewParser(tt.input).Parse()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+
+			_, err = NewRenderer().RenderWithValidation(doc, opts)
+			if tt.wantErr {
+				verr, ok := err.(*ValidationError)
+				if !ok {
+					t.Fatalf("expected ValidationError, got %v", err)
+				}
+				if verr.Position.Line != 1 {
+					t.Errorf("expected error at element position, got %v", verr.Position)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	// 解码实体后的 '&' 来自合法引用，不视为未转义；源码中的未转义 '&' 在解码后仍会被检测
+	t.Run("decoded entities", func(t *testing.T) {
+		inputs := map[string]bool{
+			`<p title="a &amp; b">x</p>`:       false,
+			`<p title="&amp;lt;">x</p>`:        false,
+			`<p title="a & b">x</p>`:           true,
+			`<p title="a &amp; b & c">x</p>`:   true,
+			`<p a="&amp;" title="a & b">x</p>`: true,
+		}
+		for input, wantErr := range inputs {
+			doc, err := NewParserWithConfig(input, XMLConfig()).Parse()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			_, err = NewRendererWithConfig(XMLConfig(), nil).RenderWithValidation(doc, opts)
+			if wantErr != (err != nil) {
+				t.Errorf("%s: expected error %v, got %v", input, wantErr, err)
+			}
+		}
+	})
+}
+
+// TestRenderToBytes 测试渲染为字节切片
+func TestRenderToBytes(t *testing.T) {
+	t.Run("matches RenderToString", func(t *testing.T) {
+		doc, err := NewParser(`<root a="1"><p>hello &amp; world</p><br/></root>`).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+
+		renderer := NewRenderer()
+		b, err := renderer.RenderToBytes(doc)
+		if err != nil {
+			t.Fatalf("RenderToBytes error: %v", err)
+		}
+		s, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("RenderToString error: %v", err)
+		}
+		if !bytes.Equal(b, []byte(s)) {
+			t.Errorf("output mismatch:\nbytes:  %q\nstring: %q", b, s)
+		}
+	})
+
+	t.Run("nil document", func(t *testing.T) {
+		b, err := NewRenderer().RenderToBytes(nil)
+		if err == nil {
+			t.Error("expected error for nil document")
+		}
+		if b != nil {
+			t.Errorf("expected nil bytes, got %q", b)
+		}
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		doc := &Document{Children: []Node{&Element{TagName: "1bad"}}}
+		renderer := NewRenderer()
+		renderer.SetValidation(&ValidationOptions{CheckWellFormed: true})
+		if _, err := renderer.RenderToBytes(doc); err == nil {
+			t.Error("expected validation error")
+		}
+	})
+}
+
+// TestOutputEncoding 测试输出编码与数字字符引用
+func TestOutputEncoding(t *testing.T) {
+	doc := &Document{
+		Children: []Node{
+			&Element{
+				TagName:    "p",
+				Attributes: map[string]string{"title": "café"},
+				Children:   []Node{&Text{Content: "café 🌍"}},
+			},
+		},
+	}
+
+	t.Run("ascii uses numeric references", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true, OutputEncoding: "ascii"})
+		output, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		expected := `<p title="caf&#233;">caf&#233; &#127757;</p>`
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("utf-8 keeps raw bytes", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true, OutputEncoding: "utf-8"})
+		output, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		expected := `<p title="café">café 🌍</p>`
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("latin1 keeps latin runes", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true, OutputEncoding: "ISO-8859-1"})
+		output, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		expected := `<p title="café">café &#127757;</p>`
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("unsupported encoding", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{OutputEncoding: "ebcdic"})
+		if _, err := renderer.RenderToString(doc); err == nil {
+			t.Error("expected error for unsupported encoding")
+		}
+	})
+
+	t.Run("xml declaration reflects encoding", func(t *testing.T) {
+		tests := []struct {
+			content  string
+			encoding string
+			expected string
+		}{
+			{`version="1.0" encoding="UTF-8"`, "ascii", `<?xml version="1.0" encoding="ascii"?>`},
+			{`version="1.0"`, "ascii", `<?xml version="1.0" encoding="ascii"?>`},
+			{`version="1.0" encoding="UTF-8"`, "utf-8", `<?xml version="1.0" encoding="UTF-8"?>`},
+			{`version="1.0"`, "utf-8", `<?xml version="1.0"?>`},
+		}
+		for _, tt := range tests {
+			pi := &Document{Children: []Node{&ProcessingInstruction{Target: "xml", Content: tt.content}}}
+			renderer := NewRendererWithOptions(&RenderOptions{
+				CompactMode:        true,
+				IncludeDeclaration: true,
+				OutputEncoding:     tt.encoding,
+			})
+			output, err := renderer.RenderToString(pi)
+			if err != nil {
+				t.Fatalf("render error: %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, output)
+			}
+		}
+	})
+
+	t.Run("default leaves declaration alone", func(t *testing.T) {
+		input := `<?xml version="1.0" encoding="ISO-8859-1"?><p>café</p>`
+		doc, err := NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		opts := *NewRenderer().options
+		opts.CompactMode = true
+		output, err := NewRendererWithOptions(&opts).RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		if output != input {
+			t.Errorf("expected %q, got %q", input, output)
+		}
+
+		// 补充的声明使用 DeclarationEncoding，不被默认输出编码覆盖
+		opts.ForceXMLDeclaration = true
+		opts.DeclarationEncoding = "UTF-16"
+		output, _ = NewRendererWithOptions(&opts).RenderToString(&Document{Children: []Node{NewElement("p")}})
+		if !strings.HasPrefix(output, `<?xml version="1.0" encoding="UTF-16"?>`) {
+			t.Errorf("expected DeclarationEncoding kept, got %q", output)
+		}
+	})
+}
+
+// TestUnquotedAttributes 测试指定属性不加引号输出
+func TestUnquotedAttributes(t *testing.T) {
+	renderer := NewRendererWithOptions(&RenderOptions{
+		CompactMode:        true,
+		EscapeText:         true,
+		SortAttributes:     true,
+		UnquotedAttributes: map[string]bool{"tabindex": true, "title": true},
+	})
+
+	tests := []struct {
+		name     string
+		attrs    map[string]string
+		expected string
+	}{
+		{
+			name:     "listed attribute unquoted, others quoted",
+			attrs:    map[string]string{"tabindex": "1", "class": "btn"},
+			expected: `<input class="btn" tabindex=1 />`,
+		},
+		{
+			name:     "whitespace forces quoting",
+			attrs:    map[string]string{"title": "two words"},
+			expected: `<input title="two words" />`,
+		},
+		{
+			name:     "special characters force quoting",
+			attrs:    map[string]string{"title": "a=b"},
+			expected: `<input title="a=b" />`,
+		},
+		{
+			name:     "backtick forces quoting",
+			attrs:    map[string]string{"title": "a`b"},
+			expected: "<input title=\"a`b\" />",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Children: []Node{&Element{TagName: "input", Attributes: tt.attrs, SelfClose: true}}}
+			output, err := renderer.RenderToString(doc)
+			if err != nil {
+				t.Fatalf("render error: %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+// TestInlineElements 测试内联元素的单行渲染
+func TestInlineElements(t *testing.T) {
+	doc := &Document{
+		Children: []Node{
+			&Element{
+				TagName: "div",
+				Children: []Node{
+					&Element{
+						TagName: "p",
+						Children: []Node{
+							&Text{Content: "Hello "},
+							&Element{TagName: "b", Children: []Node{&Text{Content: "world"}}},
+							&Text{Content: "!"},
+						},
+					},
+					&Element{
+						TagName:  "section",
+						Children: []Node{&Element{TagName: "h1", Children: []Node{&Text{Content: "Title"}}}},
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("mixed inline content on one line", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{
+			Indent:         "  ",
+			InlineElements: map[string]bool{"b": true, "i": true},
+		})
+		output, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+
+		expected := "<div>\n" +
+			"  <p>Hello <b>world</b>!</p>\n" +
+			"  <section>\n" +
+			"    <h1>\n" +
+			"      Title\n" +
+			"    </h1>\n" +
+			"  </section>\n" +
+			"</div>\n"
+		if output != expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+		}
+		if renderer.options.CompactMode {
+			t.Error("CompactMode should be restored after inline rendering")
+		}
+	})
+
+	t.Run("block child disables inline rendering", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{
+			Indent:         "  ",
+			InlineElements: map[string]bool{"b": true},
+		})
+		mixed := &Document{Children: []Node{&Element{
+			TagName: "p",
+			Children: []Node{
+				&Text{Content: "a"},
+				&Element{TagName: "b", Children: []Node{&Element{TagName: "div"}}},
+			},
+		}}}
+		output, _ := renderer.RenderToString(mixed)
+		if strings.Contains(output, "<p>a<b>") || !strings.Contains(output, "\n    <div>") {
+			t.Errorf("expected block layout, got:\n%s", output)
+		}
+	})
+
+	t.Run("no inline elements configured", func(t *testing.T) {
+		output, _ := NewRenderer().RenderToString(doc)
+		if strings.Contains(output, "<p>Hello <b>") {
+			t.Errorf("inline rendering should be opt-in, got:\n%s", output)
+		}
+	})
+}
+
+// TestEmptyValueStyle 测试空值属性与布尔属性的区分渲染
+func TestEmptyValueStyle(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		style    EmptyValueStyle
+		expected string
+	}{
+		{"preserve explicit empty", `<e class=""/>`, PreserveEmptyValue, `<e class="" />`},
+		{"preserve boolean", `<e class/>`, PreserveEmptyValue, `<e class />`},
+		{"preserve mixed", `<e a="" b c=''/>`, PreserveEmptyValue, `<e a="" b c="" />`},
+		{"force bare", `<e a="" b/>`, BareEmptyValue, `<e a b />`},
+		{"force quoted", `<e a="" b/>`, QuotedEmptyValue, `<e a="" b="" />`},
+		{"later value overrides", `<e a="" a/>`, PreserveEmptyValue, `<e a />`},
+		{"html5 boolean bare", `<input checked="checked" disabled=""/>`, HTML5BooleanValue, `<input checked disabled />`},
+		{"html5 non-boolean quoted", `<input data-x="" hidden value="v" title/>`, HTML5BooleanValue, `<input data-x="" hidden value="v" title="" />`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewParser(tt.input).Parse()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			renderer := NewRendererWithOptions(&RenderOptions{
+				CompactMode:     true,
+				EmptyValueStyle: tt.style,
+			})
+			output, err := renderer.RenderToString(doc)
+			if err != nil {
+				t.Fatalf("render error: %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+
+	t.Run("manually built element stays bare", func(t *testing.T) {
+		elem := &Element{TagName: "input", Attributes: map[string]string{"disabled": ""}, SelfClose: true}
+		output, _ := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderElement(elem)
+		if output != `<input disabled />` {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+
+	t.Run("programmatic empty value stays quoted", func(t *testing.T) {
+		elem := NewElement("img", Attr{Key: "src", Value: "a.png"})
+		elem.SelfClose = true
+		elem.SetAttribute("alt", "")
+		renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true})
+		if output, _ := renderer.RenderElement(elem); output != `<img src="a.png" alt="" />` {
+			t.Errorf("unexpected output %q", output)
+		}
+
+		// 之后设置非空值时不再保留空值标记
+		elem.SetAttribute("alt", "x")
+		elem.Attributes["alt"] = ""
+		if output, _ := renderer.RenderElement(elem); output != `<img src="a.png" alt />` {
+			t.Errorf("expected stale empty marker cleared, got %q", output)
+		}
+
+		doc, err := NewDocumentBuilder().Element("img").Attr("alt", "").End().Build()
+		if err != nil {
+			t.Fatalf("build error: %v", err)
+		}
+		if output, _ := renderer.RenderToString(doc); output != `<img alt=""></img>` {
+			t.Errorf("expected builder empty value quoted, got %q", output)
+		}
+	})
+}
+
+// TestAssumePreEscaped 测试未解码实体的文本不被重复转义
+func TestAssumePreEscaped(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"pure entity", `<p>&amp;</p>`, `<p>&amp;</p>`},
+		{"mixed entities", `<p>a &lt;b&gt; &#169; &#x263A;</p>`, `<p>a &lt;b&gt; &#169; &#x263A;</p>`},
+		{"raw ampersand still escaped", `<p>Tom & Jerry</p>`, `<p>Tom &amp; Jerry</p>`},
+		{"invalid reference escaped", `<p>&#xZZ; &;</p>`, `<p>&amp;#xZZ; &amp;;</p>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewParser(tt.input).Parse()
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			renderer := NewRendererWithOptions(&RenderOptions{
+				CompactMode:      true,
+				EscapeText:       true,
+				AssumePreEscaped: true,
+			})
+			output, err := renderer.RenderToString(doc)
+			if err != nil {
+				t.Fatalf("render error: %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+
+	t.Run("default double escapes", func(t *testing.T) {
+		doc, _ := NewParser(`<p>&amp;</p>`).Parse()
+		output, _ := NewRendererWithOptions(&RenderOptions{CompactMode: true, EscapeText: true}).RenderToString(doc)
+		if output != `<p>&amp;amp;</p>` {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+
+	t.Run("decoded parse round trips without option", func(t *testing.T) {
+		doc, _ := NewParserWithConfig(`<p>&amp;</p>`, XMLConfig()).Parse()
+		output, _ := NewRendererWithOptions(&RenderOptions{CompactMode: true, EscapeText: true}).RenderToString(doc)
+		if output != `<p>&amp;</p>` {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+
+	t.Run("attribute values", func(t *testing.T) {
+		input := `<a title="a &amp; b" alt="x & y &#169;"></a>`
+		doc, err := NewParser(input).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		renderer := NewRendererWithOptions(&RenderOptions{
+			CompactMode:      true,
+			EscapeText:       true,
+			AssumePreEscaped: true,
+		})
+		output, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		expected := `<a title="a &amp; b" alt="x &amp; y &#169;"></a>`
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("attribute round trip", func(t *testing.T) {
+		input := `<a title="a &amp; b"></a>`
+		doc, _ := NewParser(input).Parse()
+		output, _ := NewRendererWithOptions(&RenderOptions{
+			CompactMode:      true,
+			EscapeText:       true,
+			AssumePreEscaped: true,
+		}).RenderToString(doc)
+		if output != input {
+			t.Errorf("expected %q, got %q", input, output)
+		}
+	})
+}
+
+// TestNewlineStyle 测试可配置的换行符
+func TestNewlineStyle(t *testing.T) {
+	doc := &Document{Children: []Node{
+		&Comment{Content: "c"},
+		NewElement("root").WithChildren(
+			NewElement("p").WithChildren(NewText("hello")),
+			NewElement("pre").WithChildren(NewText("line1\nline2\r\nline3")),
+			&Element{TagName: "br", SelfClose: true},
+		),
+	}}
+
+	t.Run("CRLF everywhere", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{Indent: "  ", Newline: "\r\n"})
+		output, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+
+		if strings.Count(output, "\r\n") == 0 {
+			t.Fatalf("expected CRLF line breaks, got %q", output)
+		}
+		if bare := strings.Count(strings.ReplaceAll(output, "\r\n", ""), "\n"); bare != 0 {
+			t.Errorf("found %d bare LF in output %q", bare, output)
+		}
+		if strings.Contains(strings.ReplaceAll(output, "\r\n", ""), "\r") {
+			t.Errorf("found bare CR in output %q", output)
+		}
+
+		lf, _ := NewRendererWithOptions(&RenderOptions{Indent: "  "}).RenderToString(doc)
+		if normalizeLineEndings(output) != normalizeLineEndings(lf) {
+			t.Errorf("CRLF output differs from LF output beyond line breaks:\n%q\n%q", output, lf)
+		}
+	})
+
+	t.Run("default LF", func(t *testing.T) {
+		output, _ := NewRenderer().RenderToString(doc)
+		if strings.Contains(strings.ReplaceAll(output, "line2\r\n", ""), "\r") {
+			t.Errorf("unexpected CR in default output %q", output)
+		}
+	})
+
+	t.Run("invalid newline", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{Newline: "\n\n"})
+		if _, err := renderer.RenderToString(doc); err == nil {
+			t.Error("expected error for invalid newline")
+		}
+	})
+}
+
+// TestTextTransform 测试文本变换钩子
+func TestTextTransform(t *testing.T) {
+	doc := &Document{Children: []Node{
+		NewElement("p", Attr{"title", "keep"}).WithChildren(
+			NewText("a < b"),
+			NewElement("b").WithChildren(NewText("tom & jerry")),
+		),
+	}}
+
+	t.Run("transform before escaping", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{
+			CompactMode:   true,
+			EscapeText:    true,
+			TextTransform: strings.ToUpper,
+		})
+		output, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		expected := `<p title="keep">A &lt; B<b>TOM &amp; JERRY</b></p>`
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("transformed characters are escaped", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{
+			CompactMode: true,
+			EscapeText:  true,
+			TextTransform: func(s string) string {
+				return strings.ReplaceAll(s, "b", "<redacted>")
+			},
+		})
+		output, _ := renderer.RenderElement(NewElement("p").WithChildren(NewText("abc")))
+		if output != `<p>a&lt;redacted&gt;c</p>` {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+
+	t.Run("nil transform", func(t *testing.T) {
+		output, _ := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderToString(doc)
+		if !strings.Contains(output, "tom & jerry") {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+}
+
+// TestForceXMLDeclaration 测试在缺少声明时补充 XML 声明
+func TestForceXMLDeclaration(t *testing.T) {
+	doc, err := NewParser(`<root><item/></root>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	t.Run("prepends declaration", func(t *testing.T) {
+		output, err := NewRendererWithOptions(&RenderOptions{
+			CompactMode:         true,
+			IncludeDeclaration:  true,
+			ForceXMLDeclaration: true,
+		}).RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		expected := `<?xml version="1.0" encoding="UTF-8"?><root><item /></root>`
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("custom version and encoding", func(t *testing.T) {
+		output, _ := NewRendererWithOptions(&RenderOptions{
+			Indent:              "  ",
+			ForceXMLDeclaration: true,
+			DeclarationVersion:  "1.1",
+			DeclarationEncoding: "ISO-8859-1",
+		}).RenderToString(doc)
+		if !strings.HasPrefix(output, "<?xml version=\"1.1\" encoding=\"ISO-8859-1\"?>\n<root>") {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+
+	t.Run("no duplication", func(t *testing.T) {
+		withDecl := &Document{Children: []Node{
+			&ProcessingInstruction{Target: "xml", Content: `version="1.0" encoding="UTF-8"`},
+			&Element{TagName: "root"},
+		}}
+		output, _ := NewRendererWithOptions(&RenderOptions{
+			CompactMode:         true,
+			IncludeDeclaration:  true,
+			ForceXMLDeclaration: true,
+		}).RenderToString(withDecl)
+		if strings.Count(output, "<?xml") != 1 {
+			t.Errorf("expected a single declaration, got %q", output)
+		}
+		expected := `<?xml version="1.0" encoding="UTF-8"?><root></root>`
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+
+	t.Run("replaces skipped declaration", func(t *testing.T) {
+		withDecl := &Document{Children: []Node{
+			&ProcessingInstruction{Target: "xml", Content: `version="1.0"`},
+			&Element{TagName: "root"},
+		}}
+		output, _ := NewRendererWithOptions(&RenderOptions{
+			CompactMode:         true,
+			ForceXMLDeclaration: true,
+		}).RenderToString(withDecl)
+		if output != `<?xml version="1.0" encoding="UTF-8"?><root></root>` {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+}
+
+// TestNodeFilter 测试渲染时跳过或替换节点
+func TestNodeFilter(t *testing.T) {
+	doc, err := NewParser(`<div><!-- note --><p>a <b>bold</b> b</p><script>x()</script></div>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	filter := func(n Node) (bool, Node) {
+		switch node := n.(type) {
+		case *Comment:
+			return false, nil
+		case *Element:
+			switch node.TagName {
+			case "script":
+				return false, nil
+			case "b":
+				return true, &Element{TagName: "strong", Attributes: node.Attributes, Children: node.Children}
+			}
+		}
+		return true, nil
+	}
+
+	output, err := NewRendererWithOptions(&RenderOptions{
+		CompactMode: true,
+		EscapeText:  true,
+		NodeFilter:  filter,
+	}).RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	expected := `<div><p>a<strong>bold</strong>b</p></div>`
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+
+	// 原文档不受影响
+	if !strings.Contains(NewRendererWithOptions(&RenderOptions{CompactMode: true}).Render(doc), "<b>bold</b>") {
+		t.Error("expected original document to be unchanged")
+	}
+
+	t.Run("filter applies to replacement children", func(t *testing.T) {
+		doc, _ := NewParser(`<b><!-- c -->x</b>`).Parse()
+		output, _ := NewRendererWithOptions(&RenderOptions{CompactMode: true, NodeFilter: filter}).RenderToString(doc)
+		if output != `<strong>x</strong>` {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+}
+
+// TestTopLevelDeclarationOrder 测试顶层声明、注释与根元素的顺序保持
+func TestTopLevelDeclarationOrder(t *testing.T) {
+	doc := &Document{Children: []Node{
+		&Comment{Content: " header "},
+		&ProcessingInstruction{Target: "xml", Content: `version="1.0"`},
+		&Doctype{Content: "html"},
+		&Comment{Content: " after doctype "},
+		&Element{TagName: "html", Children: []Node{&Text{Content: "x"}}},
+	}}
+
+	tests := []struct {
+		name     string
+		opts     *RenderOptions
+		expected string
+	}{
+		{
+			name:     "all declarations",
+			opts:     &RenderOptions{Indent: "  ", IncludeDeclaration: true},
+			expected: "<!-- header -->\n<?xml version=\"1.0\"?>\n<!DOCTYPE html>\n<!-- after doctype -->\n<html>\n  x\n</html>\n",
+		},
+		{
+			name:     "declarations dropped",
+			opts:     &RenderOptions{Indent: "  "},
+			expected: "<!-- header -->\n<!-- after doctype -->\n<html>\n  x\n</html>\n",
+		},
+		{
+			name:     "compact",
+			opts:     &RenderOptions{CompactMode: true, IncludeDeclaration: true},
+			expected: `<!-- header --><?xml version="1.0"?><!DOCTYPE html><!-- after doctype --><html>x</html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := NewRendererWithOptions(tt.opts).RenderToString(doc)
+			if err != nil {
+				t.Fatalf("render error: %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+
+	t.Run("leading declaration dropped", func(t *testing.T) {
+		doc := &Document{Children: []Node{
+			&Doctype{Content: "html"},
+			&Comment{Content: " c "},
+			&Element{TagName: "html"},
+		}}
+		output, _ := NewRendererWithOptions(&RenderOptions{Indent: "  "}).RenderToString(doc)
+		if output != "<!-- c -->\n<html></html>\n" {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+
+	t.Run("parsed comment first", func(t *testing.T) {
+		doc, err := NewParser("<!-- first -->\n<root><item/></root>").Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		output, _ := NewRendererWithOptions(&RenderOptions{Indent: "  "}).RenderToString(doc)
+		expected := "<!--first-->\n<root>\n  <item />\n</root>\n"
+		if output != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	})
+}
+
+// TestMaxLineWidthTabWidth 测试按制表符宽度计算开始标签宽度并换行
+func TestMaxLineWidthTabWidth(t *testing.T) {
+	doc, err := NewParser(`<root><item id="x" class="abc"/><p>text</p></root>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	render := func(tabWidth int) string {
+		output, err := NewRendererWithOptions(&RenderOptions{
+			Indent:       "\t",
+			MaxLineWidth: 30,
+			TabWidth:     tabWidth,
+		}).RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		return output
+	}
+
+	// "\t<item id="x" class="abc" />" 占 8+27 列，超过 30 列
+	wrapped := "<root>\n\t<item\n\t\tid=\"x\"\n\t\tclass=\"abc\" />\n\t<p>\n\t\ttext\n\t</p>\n</root>\n"
+	if output := render(8); output != wrapped {
+		t.Errorf("expected wrapped output %q, got %q", wrapped, output)
+	}
+
+	// 制表符宽度为 2 时只占 29 列，不换行
+	unwrapped := "<root>\n\t<item id=\"x\" class=\"abc\" />\n\t<p>\n\t\ttext\n\t</p>\n</root>\n"
+	if output := render(2); output != unwrapped {
+		t.Errorf("expected unwrapped output %q, got %q", unwrapped, output)
+	}
+
+	// 默认制表符宽度为 4：4+27=31 列，换行
+	if output := render(0); output != wrapped {
+		t.Errorf("expected default tab width to wrap, got %q", output)
+	}
+
+	t.Run("no limit", func(t *testing.T) {
+		output, _ := NewRendererWithOptions(&RenderOptions{Indent: "\t", TabWidth: 8}).RenderToString(doc)
+		if output != unwrapped {
+			t.Errorf("expected no wrapping without MaxLineWidth, got %q", output)
+		}
+	})
+}
+
+// TestInitialDepth 测试从非零深度开始渲染
+func TestInitialDepth(t *testing.T) {
+	doc, err := NewParser(`<!-- note --><a><b>x</b></a><c/>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	renderer := NewRendererWithOptions(&RenderOptions{Indent: "  ", InitialDepth: 2})
+	output, err := renderer.RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	expected := "    <!--note-->\n    <a>\n      <b>\n        x\n      </b>\n    </a>\n    <c />\n"
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+
+	t.Run("single element", func(t *testing.T) {
+		output, err := renderer.RenderElement(doc.Children[2].(*Element))
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		if output != "    <c />\n" {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+}
+
+// TestValidateXMLDeclarationPlacement 测试 XML 声明位置校验
+func TestValidateXMLDeclarationPlacement(t *testing.T) {
+	validation := &ValidationOptions{CheckWellFormed: true}
+	renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true, IncludeDeclaration: true})
+
+	t.Run("declaration first", func(t *testing.T) {
+		doc := &Document{Children: []Node{
+			&ProcessingInstruction{Target: "xml", Content: `version="1.0"`},
+			&Element{TagName: "root", Children: []Node{
+				&ProcessingInstruction{Target: "xml-stylesheet", Content: `href="a.xsl"`},
+			}},
+		}}
+		if _, err := renderer.RenderWithValidation(doc, validation); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name string
+		doc  *Document
+		pos  Position
+	}{
+		{
+			name: "after comment",
+			doc: &Document{Children: []Node{
+				&Comment{Content: "c"},
+				&ProcessingInstruction{Target: "xml", Content: `version="1.0"`, Pos: Position{Line: 2, Column: 1}},
+				&Element{TagName: "root"},
+			}},
+			pos: Position{Line: 2, Column: 1},
+		},
+		{
+			name: "inside element",
+			doc: &Document{Children: []Node{
+				&Element{TagName: "root", Children: []Node{
+					&ProcessingInstruction{Target: "xml", Content: `version="1.0"`, Pos: Position{Line: 3, Column: 5}},
+				}},
+			}},
+			pos: Position{Line: 3, Column: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderer.RenderWithValidation(tt.doc, validation)
+			validationErr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("expected ValidationError, got %v", err)
+			}
+			if validationErr.Message != "XML declaration allowed only at the start of the document" {
+				t.Errorf("unexpected message %q", validationErr.Message)
+			}
+			if validationErr.Position != tt.pos || validationErr.NodeType != NodeTypeProcessingInstruction {
+				t.Errorf("unexpected position %v or node type %v", validationErr.Position, validationErr.NodeType)
+			}
+		})
+	}
+
+	t.Run("not checked without CheckWellFormed", func(t *testing.T) {
+		if _, err := renderer.RenderWithValidation(tests[0].doc, &ValidationOptions{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestGroupConsecutiveVoids(t *testing.T) {
+	config := HTMLConfig()
+	doc, err := NewParserWithConfig(`<div><p>a</p><br><br><br><p>b</p></div>`, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	render := func(group bool) string {
+		renderer := NewRendererWithConfig(config, &RenderOptions{
+			Indent:                "  ",
+			EmptyElementStyle:     VoidElementStyle,
+			GroupConsecutiveVoids: group,
+		})
+		out, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		return out
+	}
+
+	grouped := render(true)
+	if !strings.Contains(grouped, "\n  <br><br><br>\n") {
+		t.Errorf("expected voids on one line, got:\n%s", grouped)
+	}
+
+	separate := render(false)
+	if strings.Contains(separate, "<br><br>") || strings.Count(separate, "<br>") != 3 {
+		t.Errorf("expected voids on separate lines, got:\n%s", separate)
+	}
+}
+
+func TestSelfClosingElementWithChildren(t *testing.T) {
+	newDoc := func() *Document {
+		item := NewElement("item").WithChildren(NewText("lost"))
+		item.SelfClose = true
+		item.Pos = Position{Line: 2, Column: 3}
+		return &Document{Children: []Node{NewElement("root").WithChildren(item)}}
+	}
+
+	t.Run("validation error", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true})
+		_, err := renderer.RenderWithValidation(newDoc(), &ValidationOptions{CheckWellFormed: true})
+		validationErr, ok := err.(*ValidationError)
+		if !ok {
+			t.Fatalf("expected ValidationError, got %v", err)
+		}
+		if validationErr.Message != "self-closing element <item> has children" {
+			t.Errorf("unexpected message %q", validationErr.Message)
+		}
+		if validationErr.Position.Line != 2 || validationErr.Position.Column != 3 {
+			t.Errorf("unexpected position %v", validationErr.Position)
+		}
+	})
+
+	t.Run("children dropped by default", func(t *testing.T) {
+		output, err := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderToString(newDoc())
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		if output != "<root><item /></root>" {
+			t.Errorf("unexpected output %q", output)
+		}
+	})
+
+	t.Run("paired fallback", func(t *testing.T) {
+		renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true, PairSelfClosingWithChildren: true})
+		output, err := renderer.RenderToString(newDoc())
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		if output != "<root><item>lost</item></root>" {
+			t.Errorf("unexpected output %q", output)
+		}
+
+		empty := &Element{TagName: "br", SelfClose: true}
+		if output, _ := renderer.RenderElement(empty); output != "<br />" {
+			t.Errorf("childless element should stay self-closing, got %q", output)
+		}
+	})
+}
+
+func TestOmitRedundantNamespaces(t *testing.T) {
+	input := `<root xmlns="urn:a" xmlns:x="urn:x"><x:item xmlns:x="urn:x"><inner xmlns="urn:a" xmlns:x="urn:other"/></x:item><leaf xmlns="urn:b"><deep xmlns="urn:b"/></leaf></root>`
+	doc, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	render := func(omit bool) string {
+		output, err := NewRendererWithOptions(&RenderOptions{
+			CompactMode:             true,
+			SortAttributes:          true,
+			OmitRedundantNamespaces: omit,
+		}).RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		return output
+	}
+
+	expected := `<root xmlns="urn:a" xmlns:x="urn:x"><x:item><inner xmlns:x="urn:other" /></x:item><leaf xmlns="urn:b"><deep /></leaf></root>`
+	if output := render(true); output != expected {
+		t.Errorf("expected %s, got %s", expected, output)
+	}
+	if output := render(false); strings.Count(output, "xmlns") != 7 {
+		t.Errorf("expected all declarations without the option, got %s", output)
+	}
+}
+
+func TestCompactSmallElements(t *testing.T) {
+	long := strings.Repeat("long text ", 8)
+	doc, err := NewParser(`<div><b>x</b><p>` + long + `</p></div>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	render := func(compactSmall bool) string {
+		output, err := NewRendererWithOptions(&RenderOptions{
+			Indent:               "  ",
+			EscapeText:           true,
+			CompactSmallElements: compactSmall,
+		}).RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		return output
+	}
+
+	output := render(true)
+	if !strings.Contains(output, "\n  <b>x</b>\n") {
+		t.Errorf("expected small element on one line, got:\n%s", output)
+	}
+	if !strings.Contains(output, "  <p>\n    "+strings.TrimSpace(long)+"\n  </p>\n") {
+		t.Errorf("expected long element expanded, got:\n%s", output)
+	}
+
+	if output := render(false); strings.Contains(output, "<b>x</b>") {
+		t.Errorf("expected small element expanded without the option, got:\n%s", output)
+	}
+}
+
+func TestHTMLCompatStyle(t *testing.T) {
+	doc, err := NewParserWithConfig(`<body><div/><br/><p>a<br>b</p><span class="x"/></body>`, HTMLConfig()).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	renderer := NewRendererWithConfig(HTMLConfig(), &RenderOptions{
+		CompactMode:       true,
+		EscapeText:        true,
+		EmptyElementStyle: HTMLCompatStyle,
+	})
+	output, err := renderer.RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+
+	expected := `<body><div></div><br><p>a<br>b</p><span class="x"></span></body>`
+	if output != expected {
+		t.Errorf("expected %s, got %s", expected, output)
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	doc := &Document{Children: []Node{
+		&Element{TagName: "root", Pos: Position{Line: 1, Column: 1}, Children: []Node{
+			&Element{TagName: "1bad", Pos: Position{Line: 2, Column: 3}},
+			&Element{TagName: "ok", Attributes: map[string]string{"href": "a&b"}, Pos: Position{Line: 3, Column: 3}},
+			&Text{Content: string([]byte{0xff}), Pos: Position{Line: 4, Column: 3}},
+		}},
+	}}
+	renderer := NewRenderer()
+
+	err := renderer.ValidateAll(doc, &ValidationOptions{CheckWellFormed: true, CheckEncoding: true})
+	var all ValidationErrors
+	if !errors.As(err, &all) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 errors, got %d: %v", len(all), all)
+	}
+
+	expected := "3 validation errors:\n" +
+		"  - validation error at line 2, column 3: invalid tag name: 1bad\n" +
+		"  - validation error at line 3, column 3: unescaped '&' in value of attribute: href\n" +
+		"  - validation error at line 4, column 3: invalid UTF-8 encoding in text content"
+	if err.Error() != expected {
+		t.Errorf("unexpected message:\n%s", err.Error())
+	}
+
+	var first *ValidationError
+	if !errors.As(err, &first) || first != all[0] {
+		t.Errorf("expected errors.As to unwrap the first *ValidationError, got %v", first)
+	}
+
+	single := ValidationErrors{all[1]}
+	if single.Error() != all[1].Error() {
+		t.Errorf("single error should format as the error itself, got %q", single.Error())
+	}
+
+	if err := renderer.ValidateAll(&Document{Children: []Node{NewElement("root")}}, &ValidationOptions{CheckWellFormed: true}); err != nil {
+		t.Errorf("expected nil for valid document, got %v", err)
+	}
+}
+
+func TestEscapeAttributeWhitespace(t *testing.T) {
+	elem := NewElement("e", Attr{Key: "title", Value: "line1\nline2\tx\r"}, Attr{Key: "id", Value: "a b"})
+	elem.SelfClose = true
+
+	output, err := NewRendererWithOptions(&RenderOptions{
+		CompactMode:               true,
+		EscapeText:                true,
+		EscapeAttributeWhitespace: true,
+	}).RenderElement(elem)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	expected := `<e title="line1&#10;line2&#9;x&#13;" id="a b" />`
+	if output != expected {
+		t.Errorf("expected %s, got %s", expected, output)
+	}
+
+	output, _ = NewRendererWithOptions(&RenderOptions{CompactMode: true, EscapeText: true}).RenderElement(elem)
+	if !strings.Contains(output, "line1\nline2\tx\r") {
+		t.Errorf("expected raw whitespace without the option, got %q", output)
+	}
+}
+
+func TestNormalizeClassAttribute(t *testing.T) {
+	elem := NewElement("e", Attr{Key: "class", Value: " b a  b "}, Attr{Key: "title", Value: "b a b"})
+	elem.SelfClose = true
+
+	output, err := NewRendererWithOptions(&RenderOptions{
+		CompactMode:             true,
+		NormalizeClassAttribute: true,
+	}).RenderElement(elem)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	expected := `<e class="a b" title="b a b" />`
+	if output != expected {
+		t.Errorf("expected %s, got %s", expected, output)
+	}
+	if elem.Attributes["class"] != " b a  b " {
+		t.Errorf("rendering should not modify the element, got %q", elem.Attributes["class"])
+	}
+
+	output, _ = NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderElement(elem)
+	if !strings.Contains(output, `class=" b a  b "`) {
+		t.Errorf("expected class untouched without the option, got %s", output)
+	}
+}
+
+func TestCDATAForMarkupText(t *testing.T) {
+	code := "if (a < b && c > d) { run(); }"
+	elem := NewElement("script")
+	elem.WithChildren(NewText(code))
+	doc := &Document{Children: []Node{elem}}
+
+	output, err := NewRendererWithOptions(&RenderOptions{
+		CompactMode:        true,
+		EscapeText:         true,
+		CDATAForMarkupText: true,
+	}).RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	expected := "<script><![CDATA[" + code + "]]></script>"
+	if output != expected {
+		t.Fatalf("expected %s, got %s", expected, output)
+	}
+
+	reparsed, err := NewParser(output).Parse()
+	if err != nil {
+		t.Fatalf("reparse error: %v", err)
+	}
+	cdata, ok := reparsed.Children[0].(*Element).Children[0].(*CDATA)
+	if !ok || cdata.Content != code {
+		t.Errorf("expected CDATA with original content, got %#v", reparsed.Children[0].(*Element).Children[0])
+	}
+
+	t.Run("splits embedded terminator", func(t *testing.T) {
+		elem := NewElement("x")
+		elem.WithChildren(NewText("a]]>b<"))
+		output, _ := NewRendererWithOptions(&RenderOptions{CompactMode: true, CDATAForMarkupText: true}).RenderElement(elem)
+		if output != "<x><![CDATA[a]]]]><![CDATA[>b<]]></x>" {
+			t.Errorf("unexpected split output: %s", output)
+		}
+	})
+
+	t.Run("plain text unchanged", func(t *testing.T) {
+		elem := NewElement("x")
+		elem.WithChildren(NewText("plain"))
+		output, _ := NewRendererWithOptions(&RenderOptions{CompactMode: true, CDATAForMarkupText: true}).RenderElement(elem)
+		if output != "<x>plain</x>" {
+			t.Errorf("expected plain text, got %s", output)
+		}
+	})
+
+	t.Run("falls back to escaping when encoding is needed", func(t *testing.T) {
+		elem := NewElement("x")
+		elem.WithChildren(NewText("a < é"))
+		output, err := NewRendererWithOptions(&RenderOptions{
+			CompactMode:        true,
+			EscapeText:         true,
+			CDATAForMarkupText: true,
+			OutputEncoding:     "ascii",
+		}).RenderElement(elem)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		if output != "<x>a &lt; &#233;</x>" {
+			t.Errorf("expected escaped ascii output, got %s", output)
+		}
+
+		output, _ = NewRendererWithOptions(&RenderOptions{
+			CompactMode:        true,
+			EscapeText:         true,
+			CDATAForMarkupText: true,
+			ForceEncodeRunes:   []rune{'\u00a0'},
+		}).RenderElement(NewElement("x").WithChildren(NewText("a &\u00a0b")))
+		if output != "<x>a &amp;&#160;b</x>" {
+			t.Errorf("expected forced rune encoded, got %s", output)
+		}
+	})
+}
+
+func TestRenderBooleanAttributesInSourceOrder(t *testing.T) {
+	input := `<input required type="text" checked>`
+	doc, err := NewParserWithConfig(input, HTMLConfig()).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	renderer := NewRendererWithConfig(HTMLConfig(), &RenderOptions{
+		CompactMode:       true,
+		EmptyElementStyle: HTMLCompatStyle,
+		EmptyValueStyle:   HTML5BooleanValue,
+	})
+	output, err := renderer.RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if output != input {
+		t.Errorf("expected %s, got %s", input, output)
+	}
+
+	// 布尔属性显式赋值时同样按源码位置输出为裸属性
+	doc, _ = NewParserWithConfig(`<input checked="checked" type="text" disabled="">`, HTMLConfig()).Parse()
+	output, _ = renderer.RenderToString(doc)
+	if expected := `<input checked type="text" disabled>`; output != expected {
+		t.Errorf("expected %s, got %s", expected, output)
+	}
+}
+
+func TestValidateDocument(t *testing.T) {
+	opts := &ValidationOptions{CheckWellFormed: true, CheckEncoding: true}
+
+	doc, err := NewParser(`<root><item id="1">text</item></root>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if err := ValidateDocument(doc, opts); err != nil {
+		t.Errorf("expected well-formed document to validate, got %v", err)
+	}
+
+	bad := NewElement("root", Attr{Key: "href", Value: "a&b"})
+	bad.WithChildren(NewText("ok"), NewElement("1bad"))
+	err = ValidateDocument(&Document{Children: []Node{bad}}, opts)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !strings.Contains(validationErr.Message, "unescaped '&'") {
+		t.Errorf("expected first validation error, got %v", err)
+	}
+
+	br := NewElement("br")
+	br.SelfClose = true
+	if err := ValidateWithConfig(&Document{Children: []Node{br}}, HTMLConfig(), opts); err != nil {
+		t.Errorf("expected void element to validate under HTML config, got %v", err)
+	}
+
+	if err := ValidateDocument(&Document{Children: []Node{bad}}, nil); err != nil {
+		t.Errorf("expected nil options to skip checks, got %v", err)
+	}
+	if err := ValidateDocument(nil, opts); err == nil {
+		t.Error("expected error for nil document")
+	}
+}
+
+func TestMinimalAttributeEscaping(t *testing.T) {
+	value := `a > b && c < d "q" 'x'`
+	elem := NewElement("e", Attr{Key: "expr", Value: value})
+	elem.SelfClose = true
+
+	full, err := NewRendererWithOptions(&RenderOptions{CompactMode: true, EscapeText: true}).RenderElement(elem)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if expected := `<e expr="a &gt; b &amp;&amp; c &lt; d &quot;q&quot; &#39;x&#39;" />`; full != expected {
+		t.Errorf("expected %s, got %s", expected, full)
+	}
+
+	minimal, err := NewRendererWithOptions(&RenderOptions{CompactMode: true, EscapeText: true, MinimalEscaping: true}).RenderElement(elem)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if expected := `<e expr="a > b &amp;&amp; c &lt; d &quot;q&quot; 'x'" />`; minimal != expected {
+		t.Errorf("expected %s, got %s", expected, minimal)
+	}
+
+	config := DefaultConfig()
+	config.DecodeEntities = true
+	for _, output := range []string{full, minimal} {
+		doc, err := NewParserWithConfig(output, config).Parse()
+		if err != nil {
+			t.Fatalf("reparse error for %s: %v", output, err)
+		}
+		if got := doc.Children[0].(*Element).Attributes["expr"]; got != value {
+			t.Errorf("expected %q after reparse of %s, got %q", value, output, got)
+		}
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	input := `<page><header><slot name="title"/></header><main><slot name="body"/></main><footer><slot name="footer">default</slot></footer></page>`
+	doc, err := NewParser(input).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	slots := map[string][]Node{
+		"title": {NewText("Hello")},
+		"body": {
+			NewElement("p").WithChildren(NewText("one")),
+			NewElement("p").WithChildren(NewText("two")),
+		},
+	}
+
+	renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true})
+	output, err := renderer.RenderTemplate(doc, slots)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	expected := `<page><header>Hello</header><main><p>one</p><p>two</p></main><footer>default</footer></page>`
+	if output != expected {
+		t.Errorf("expected %s, got %s", expected, output)
+	}
+
+	// 模板本身不被修改，普通渲染仍输出插槽元素
+	output, _ = renderer.RenderToString(doc)
+	if !strings.Contains(output, `<slot name="body" />`) {
+		t.Errorf("expected template AST untouched, got %s", output)
+	}
+
+	t.Run("custom slot tag", func(t *testing.T) {
+		doc, _ := NewParser(`<p><hole name="x"/></p>`).Parse()
+		renderer := NewRendererWithOptions(&RenderOptions{CompactMode: true, SlotTag: "hole"})
+		output, err := renderer.RenderTemplate(doc, map[string][]Node{"x": {NewText("filled")}})
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		if output != "<p>filled</p>" {
+			t.Errorf("expected <p>filled</p>, got %s", output)
+		}
+	})
+}
+
+func TestRenderControlAttribute(t *testing.T) {
+	doc, err := NewParser(`<doc><section><p>a</p></section><nav data-render="compact" id="n"><a>1</a><a>2</a></nav></doc>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	output, err := NewRendererWithOptions(&RenderOptions{Indent: "  ", RenderControlAttribute: "data-render"}).RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	expected := "<doc>\n  <section>\n    <p>\n      a\n    </p>\n  </section>\n  <nav id=\"n\"><a>1</a><a>2</a></nav>\n</doc>\n"
+	if output != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	t.Run("expanded inside compact", func(t *testing.T) {
+		doc, _ := NewParser(`<doc><list data-render="expanded"><i>x</i></list><b>y</b></doc>`).Parse()
+		output, err := NewRendererWithOptions(&RenderOptions{CompactMode: true, Indent: "  ", RenderControlAttribute: "data-render"}).RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		expected := "<doc><list>\n  <i>\n    x\n  </i>\n</list>\n<b>y</b></doc>"
+		if output != expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+		}
+	})
+
+	t.Run("attribute kept without option", func(t *testing.T) {
+		output, _ := NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderToString(doc)
+		if !strings.Contains(output, `data-render="compact"`) {
+			t.Errorf("expected control attribute rendered as a normal attribute, got %s", output)
+		}
+	})
+
+	t.Run("preserve keeps source whitespace", func(t *testing.T) {
+		config := DefaultConfig()
+		config.TrimWhitespace = false
+		renderer := NewRendererWithOptions(&RenderOptions{Indent: "  ", RenderControlAttribute: "data-render"})
+		source := "<pre data-render=\"%s\">\n  <b> x </b>\n\t<i/>  y\n</pre>"
+
+		doc, err := NewParserWithConfig("<doc>"+fmt.Sprintf(source, "preserve")+"</doc>", config).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		output, _ := renderer.RenderToString(doc)
+		expected := "<doc>\n  <pre>\n  <b> x </b>\n\t<i />  y\n</pre>\n</doc>\n"
+		if output != expected {
+			t.Errorf("expected:\n%q\ngot:\n%q", expected, output)
+		}
+
+		// compact 子树丢弃标签之间的排版空白
+		doc, _ = NewParserWithConfig("<doc>"+fmt.Sprintf(source, "compact")+"</doc>", config).Parse()
+		output, _ = renderer.RenderToString(doc)
+		expected = "<doc>\n  <pre><b> x </b><i />  y\n</pre>\n</doc>\n"
+		if output != expected {
+			t.Errorf("expected:\n%q\ngot:\n%q", expected, output)
+		}
+	})
+}
+
+func TestRenderEstimatedSize(t *testing.T) {
+	doc, err := NewParser(`<root><item id="1">one</item><item id="2">two</item></root>`).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var expected strings.Builder
+	if err := NewRenderer().RenderToWriter(doc, &expected); err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+
+	for _, size := range []int{0, 1, 4096} {
+		opts := *NewRenderer().options
+		opts.EstimatedSize = size
+		renderer := NewRendererWithOptions(&opts)
+
+		output, err := renderer.RenderToString(doc)
+		if err != nil {
+			t.Fatalf("render error: %v", err)
+		}
+		if output != expected.String() {
+			t.Errorf("EstimatedSize %d: expected %q, got %q", size, expected.String(), output)
+		}
+		if b, _ := renderer.RenderToBytes(doc); string(b) != expected.String() {
+			t.Errorf("EstimatedSize %d: RenderToBytes output differs", size)
+		}
+	}
+}
+
+func TestForceEncodeRunes(t *testing.T) {
+	elem := NewElement("p", Attr{Key: "title", Value: "a\u200bb"})
+	elem.WithChildren(NewText("zero\u200bwidth \u200fmark é"))
+
+	renderer := NewRendererWithOptions(&RenderOptions{
+		CompactMode:      true,
+		EscapeText:       true,
+		ForceEncodeRunes: []rune{'\u200b'},
+	})
+	output, err := renderer.RenderElement(elem)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	expected := "<p title=\"a&#8203;b\">zero&#8203;width \u200fmark é</p>"
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+
+	output, _ = NewRendererWithOptions(&RenderOptions{CompactMode: true}).RenderElement(elem)
+	if strings.Contains(output, "&#8203;") {
+		t.Errorf("expected raw rune without the option, got %q", output)
+	}
+}
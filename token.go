@@ -59,6 +59,16 @@ type Token struct {
 	Value      string
 	Attributes map[string]string
 	Position   Position
+	// AttributeOrder 属性在标签中出现的顺序
+	AttributeOrder []string
+	// EmptyAttributes 显式赋值为空字符串的属性名（如 class=""），用于区分布尔属性
+	EmptyAttributes map[string]bool
+	// DecodedAttributes 值经过实体解码且源码中没有未转义 '&' 的属性名
+	DecodedAttributes map[string]bool
+	// AttributePositions 每个属性名的起始位置（仅在 TrackAttributePositions 开启时填充）
+	AttributePositions map[string]Position
+	// NamePos 结束标签中标签名的起始位置，与 Value 的长度一起构成名称区间（仅在 TrackCloseTagPositions 开启时填充）
+	NamePos Position
 }
 
 // Position 表示源码中的位置信息
@@ -66,6 +76,8 @@ type Position struct {
 	Line   int
 	Column int
 	Offset int
+	// ByteOffset 字节偏移，Offset 在 UseRuneOffsets 开启时为字符偏移
+	ByteOffset int
 }
 
 // String 返回 Token 的字符串表示
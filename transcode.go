@@ -0,0 +1,217 @@
+package markit
+
+import (
+	"fmt"
+	"io"
+)
+
+// Transcode 逐个 token 解析 input 并直接渲染到 w，不构建完整的文档树，内存占用只与元素嵌套深度有关
+// 属性转义、声明过滤和块级内容的缩进与先解析再用 r 渲染的结果一致；
+// 依赖完整子树的选项（InlineElements、CompactSmallElements、GroupConsecutiveVoids、NodeFilter、
+// RenderControlAttribute、OmitRedundantNamespaces）以及渲染验证不会生效
+func Transcode(input string, config *ParserConfig, r *Renderer, w io.Writer) error {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if r == nil {
+		r = NewRenderer()
+	}
+	if w == nil {
+		return fmt.Errorf("writer is nil")
+	}
+	if err := r.checkOptions(); err != nil {
+		return err
+	}
+
+	p := NewParserWithConfig(input, config)
+	if p.err != nil {
+		return p.err
+	}
+
+	t := &transcoder{p: p, r: r, w: w}
+	return t.run()
+}
+
+// transcoder Transcode 的状态，只保存未闭合元素的栈
+type transcoder struct {
+	p     *Parser
+	r     *Renderer
+	w     io.Writer
+	stack []*transcodeFrame
+}
+
+// transcodeFrame 一个已输出开始标签、尚未闭合的元素
+type transcodeFrame struct {
+	elem  *Element
+	depth int
+	// opened 是否已按多子节点布局在开始标签后换行
+	opened bool
+	// hasNonText 是否输出过非文本子节点，决定结束标签前是否缩进
+	hasNonText bool
+}
+
+// run 处理全部 token 直到 EOF
+func (t *transcoder) run() error {
+	p, r := t.p, t.r
+
+	if r.options.ForceXMLDeclaration {
+		declared := p.current.Type == TokenProcessingInstruction && isXMLDeclarationToken(p.current.Value)
+		if !(r.options.IncludeDeclaration && declared) {
+			if err := r.writeXMLDeclaration(t.w); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 跳过注释时保留文档开头的第一个注释
+	if p.config.SkipComments && p.config.KeepLeadingComment && p.current.Type == TokenComment {
+		node, err := p.parseComment()
+		if err != nil {
+			return err
+		}
+		if err := t.emit(node); err != nil {
+			return err
+		}
+	}
+
+	for {
+		switch {
+		case p.current.Type == TokenComment && p.config.SkipComments:
+			p.nextToken()
+		case p.current.Type == TokenEOF && len(t.stack) == 0:
+			return nil
+		case (p.current.Type == TokenCloseTag || p.current.Type == TokenEOF) && len(t.stack) > 0:
+			if err := t.closeElement(); err != nil {
+				return err
+			}
+		case p.current.Type == TokenOpenTag:
+			if err := t.openElement(); err != nil {
+				return err
+			}
+		default:
+			node, err := p.parseNode()
+			if err != nil {
+				return err
+			}
+			if node != nil {
+				if err := t.emit(node); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// depth 返回下一个子节点的渲染深度
+func (t *transcoder) depth() int {
+	if len(t.stack) == 0 {
+		return t.r.options.InitialDepth
+	}
+	return t.stack[len(t.stack)-1].depth + 1
+}
+
+// beginChild 在当前元素的第一个子节点之前按多子节点布局换行，并记录子节点类型
+func (t *transcoder) beginChild(node Node) error {
+	if len(t.stack) == 0 {
+		return nil
+	}
+	frame := t.stack[len(t.stack)-1]
+	if _, ok := node.(*Text); !ok {
+		frame.hasNonText = true
+	}
+	if frame.opened {
+		return nil
+	}
+	frame.opened = true
+	if !t.r.options.CompactMode {
+		_, err := t.w.Write([]byte(t.r.newline()))
+		return err
+	}
+	return nil
+}
+
+// emit 将完整的叶子节点（或自闭合元素）作为当前元素的子节点输出
+func (t *transcoder) emit(node Node) error {
+	if err := t.beginChild(node); err != nil {
+		return err
+	}
+	return t.r.renderNode(node, t.w, t.depth())
+}
+
+// openElement 输出开始标签，并处理 void 元素、空元素和只有一个文本子节点的元素
+func (t *transcoder) openElement() error {
+	p, r := t.p, t.r
+
+	elem, complete, err := p.openElement()
+	if err != nil {
+		return err
+	}
+	if err := t.beginChild(elem); err != nil {
+		return err
+	}
+
+	depth := t.depth()
+	if err := r.renderStartTag(elem, t.w, depth); err != nil {
+		return err
+	}
+	if complete {
+		return r.renderSelfClosingEnd(elem, t.w)
+	}
+	if _, err := t.w.Write([]byte(">")); err != nil {
+		return err
+	}
+
+	t.stack = append(t.stack, &transcodeFrame{elem: elem, depth: depth})
+	p.nsScope = elem.Namespaces
+	p.depth += nestingLevel(elem)
+
+	// 文本之后紧跟结束标签时按单个文本子节点布局输出
+	for p.config.SkipComments && p.current.Type == TokenComment {
+		p.nextToken()
+	}
+	if p.current.Type != TokenText {
+		return nil
+	}
+	node, err := p.parseText()
+	if err != nil {
+		return err
+	}
+	for p.config.SkipComments && p.current.Type == TokenComment {
+		p.nextToken()
+	}
+	if p.current.Type == TokenCloseTag {
+		t.stack[len(t.stack)-1].opened = true
+		return r.renderSingleTextChild(node.(*Text), t.w, depth)
+	}
+	return t.emit(node)
+}
+
+// closeElement 校验结束标签并输出当前元素的结尾
+func (t *transcoder) closeElement() error {
+	p, r := t.p, t.r
+	frame := t.stack[len(t.stack)-1]
+
+	if err := p.closeElement(frame.elem); err != nil {
+		return err
+	}
+	t.stack = t.stack[:len(t.stack)-1]
+	p.depth -= nestingLevel(frame.elem)
+	p.nsScope = nil
+	if len(t.stack) > 0 {
+		p.nsScope = t.stack[len(t.stack)-1].elem.Namespaces
+	}
+
+	// 结束标签前的缩进（只有在有非文本子节点时）
+	if !r.options.CompactMode && frame.hasNonText {
+		if err := r.writeIndent(t.w, frame.depth); err != nil {
+			return err
+		}
+	}
+	return r.renderEndTag(frame.elem, t.w)
+}
+
+// isXMLDeclarationToken 检查处理指令 token 的内容是否为 XML 声明
+func isXMLDeclarationToken(value string) bool {
+	target, _ := splitProcessingInstruction(value)
+	return target == "xml"
+}
@@ -0,0 +1,105 @@
+package markit
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTranscodeMatchesRender 测试流式转码与先解析再渲染的输出一致
+func TestTranscodeMatchesRender(t *testing.T) {
+	documents := []string{
+		`<root><item id="1" class="a &amp; b">one</item><item/><empty></empty></root>`,
+		`<!DOCTYPE note><!-- lead --><note><to>Ada</to><body>Hi <b>there</b> all<!-- c --></body></note>`,
+		"<doc><pre>line1\nline2</pre><p>text<br/>more</p><><x>frag</x></></doc>",
+		`<a><b><c><d>deep</d></c></b></a><tail>top-level sibling</tail>`,
+		`<p title="x &gt; y" data-v='q"q'>a &lt; b</p>`,
+		`<!-- lead --><a><!-- x -->t</a>`,
+	}
+
+	type setup struct {
+		name   string
+		config func() *ParserConfig
+		opts   *RenderOptions
+	}
+	setups := []setup{
+		{"default", DefaultConfig, nil},
+		{"compact", DefaultConfig, &RenderOptions{CompactMode: true, EscapeText: true, IncludeDeclaration: true}},
+		{"no declarations", DefaultConfig, &RenderOptions{Indent: "\t", EscapeText: true}},
+		{"skip comments", func() *ParserConfig {
+			config := DefaultConfig()
+			config.SkipComments = true
+			config.AllowEmptyTags = true
+			return config
+		}, &RenderOptions{Indent: "  ", EscapeText: true, IncludeDeclaration: true, EmptyElementStyle: PairedTagStyle}},
+		{"keep leading comment", func() *ParserConfig {
+			config := DefaultConfig()
+			config.SkipComments = true
+			config.KeepLeadingComment = true
+			config.AllowEmptyTags = true
+			return config
+		}, nil},
+		{"fragments", func() *ParserConfig {
+			config := DefaultConfig()
+			config.AllowEmptyTags = true
+			return config
+		}, &RenderOptions{Indent: "  ", EscapeText: true, MaxLineWidth: 20, SortAttributes: true}},
+	}
+
+	for _, s := range setups {
+		for i, input := range documents {
+			doc, parseErr := NewParserWithConfig(input, s.config()).Parse()
+
+			var expected, got strings.Builder
+			transcodeErr := Transcode(input, s.config(), NewRendererWithOptions(s.opts), &got)
+			if parseErr != nil {
+				if transcodeErr == nil || transcodeErr.Error() != parseErr.Error() {
+					t.Errorf("%s/%d: expected error %v, got %v", s.name, i, parseErr, transcodeErr)
+				}
+				continue
+			}
+			if transcodeErr != nil {
+				t.Errorf("%s/%d: transcode error: %v", s.name, i, transcodeErr)
+				continue
+			}
+			if err := NewRendererWithOptions(s.opts).RenderToWriter(doc, &expected); err != nil {
+				t.Fatalf("%s/%d: render error: %v", s.name, i, err)
+			}
+			if got.String() != expected.String() {
+				t.Errorf("%s/%d: output differs\nexpected:\n%s\ngot:\n%s", s.name, i, expected.String(), got.String())
+			}
+		}
+	}
+}
+
+// TestTranscodeVoidElements 测试 HTML void 元素的流式转码
+func TestTranscodeVoidElements(t *testing.T) {
+	input := `<div><img src="a.png"><br></br><p>x</p></div>`
+	config := HTMLConfig()
+	config.AllowVoidElementClose = true
+	renderer := NewRendererWithConfig(config, &RenderOptions{Indent: "  ", EscapeText: true, EmptyElementStyle: HTMLCompatStyle})
+
+	doc, err := NewParserWithConfig(input, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	expected, _ := renderer.RenderToString(doc)
+
+	var got strings.Builder
+	if err := Transcode(input, config, renderer, &got); err != nil {
+		t.Fatalf("transcode error: %v", err)
+	}
+	if got.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got.String())
+	}
+}
+
+// TestTranscodeErrors 测试流式转码的错误与先解析的错误一致
+func TestTranscodeErrors(t *testing.T) {
+	for _, input := range []string{`<a><b></a>`, `<a>`, `</a>`, `<a x="1>`} {
+		_, parseErr := NewParser(input).Parse()
+		err := Transcode(input, nil, nil, &strings.Builder{})
+		if parseErr == nil || err == nil || err.Error() != parseErr.Error() {
+			t.Errorf("%s: expected error %v, got %v", input, parseErr, err)
+		}
+	}
+}
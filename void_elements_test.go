@@ -1,6 +1,7 @@
 package markit
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -418,3 +419,89 @@ func TestVoidElementsEdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// TestVoidElementAndBooleanAttributeNames 测试枚举 void element 和布尔属性
+func TestVoidElementAndBooleanAttributeNames(t *testing.T) {
+	config := HTMLConfig()
+
+	voids := strings.Join(config.VoidElementNames(), ",")
+	expectedVoids := "area,base,br,col,embed,hr,img,input,link,meta,param,source,track,wbr"
+	if voids != expectedVoids {
+		t.Errorf("expected %s, got %s", expectedVoids, voids)
+	}
+
+	booleans := strings.Join(config.BooleanAttributeNames(), ",")
+	expectedBooleans := "autofocus,autoplay,checked,controls,defer,disabled,hidden,loop,multiple,muted,open,readonly,required,reversed,scoped,selected"
+	if booleans != expectedBooleans {
+		t.Errorf("expected %s, got %s", expectedBooleans, booleans)
+	}
+
+	t.Run("default config", func(t *testing.T) {
+		config := DefaultConfig()
+		if names := config.VoidElementNames(); len(names) != 0 {
+			t.Errorf("expected no void elements, got %v", names)
+		}
+		config.AddVoidElement("x-icon")
+		config.AddVoidElement("a-spacer")
+		if names := strings.Join(config.VoidElementNames(), ","); names != "a-spacer,x-icon" {
+			t.Errorf("expected sorted names, got %s", names)
+		}
+
+		processor := &DefaultAttributeProcessor{}
+		if strings.Join(processor.BooleanAttributeNames(), ",") != expectedBooleans {
+			t.Errorf("unexpected default boolean attributes %v", processor.BooleanAttributeNames())
+		}
+	})
+
+	t.Run("processor without names", func(t *testing.T) {
+		config := DefaultConfig()
+		config.AttributeProcessor = nil
+		if names := config.BooleanAttributeNames(); names != nil {
+			t.Errorf("expected nil, got %v", names)
+		}
+	})
+}
+
+func TestErrorOnVoidElementClose(t *testing.T) {
+	input := `<div><br></br></div>`
+
+	t.Run("stray close tag by default", func(t *testing.T) {
+		_, err := NewParserWithConfig(input, HTMLConfig()).Parse()
+		if err == nil || !strings.Contains(err.Error(), "mismatched tags: expected </div>, got </br>") {
+			t.Errorf("expected stray </br> error, got %v", err)
+		}
+	})
+
+	t.Run("skipped when allowed", func(t *testing.T) {
+		config := HTMLConfig()
+		config.AllowVoidElementClose = true
+		doc, err := NewParserWithConfig(input, config).Parse()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		div := doc.Children[0].(*Element)
+		if len(div.Children) != 1 {
+			t.Fatalf("expected 1 child, got %d", len(div.Children))
+		}
+		if br, ok := div.Children[0].(*Element); !ok || br.TagName != "br" || !br.SelfClose {
+			t.Errorf("expected self-closing <br>, got %v", div.Children[0])
+		}
+	})
+
+	t.Run("error when enabled", func(t *testing.T) {
+		config := HTMLConfig()
+		config.ErrorOnVoidElementClose = true
+		config.AllowVoidElementClose = true
+		_, err := NewParserWithConfig(input, config).Parse()
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("expected ParseError, got %v", err)
+		}
+		if parseErr.Message != "void element <br> must not have a closing tag" {
+			t.Errorf("unexpected message %q", parseErr.Message)
+		}
+		if parseErr.Position.Line != 1 || parseErr.Position.Column != 10 {
+			t.Errorf("unexpected position %v", parseErr.Position)
+		}
+	})
+}
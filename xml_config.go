@@ -0,0 +1,23 @@
+package markit
+
+// XMLConfig 创建适用于严格 XML 的配置
+// 与 DefaultConfig 相比：
+//   - 解码文本和属性值中的预定义实体与数字字符引用（DecodeEntities）
+//   - 按 XML 规范将 \r\n 和 \r 统一为 \n（NormalizeLineEndings）
+//   - 明确不定义任何 void element，<br> 这类 HTML 简写必须显式闭合
+func XMLConfig() *ParserConfig {
+	config := &ParserConfig{
+		CaseSensitive:        true,                     // XML区分大小写
+		CoreMatcher:          NewCoreProtocolMatcher(), // 必须设置核心协议匹配器
+		AttributeProcessor:   &DefaultAttributeProcessor{},
+		TrimWhitespace:       true,
+		SkipComments:         false,
+		AllowEmptyElements:   true,
+		AllowSelfCloseTags:   true,
+		DecodeEntities:       true,
+		NormalizeLineEndings: true,
+		VoidElements:         make(map[string]bool),
+	}
+
+	return config
+}
@@ -0,0 +1,176 @@
+package markit
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestXMLConfig 测试严格 XML 配置预设
+func TestXMLConfig(t *testing.T) {
+	t.Run("flag values", func(t *testing.T) {
+		config := XMLConfig()
+		if !config.CaseSensitive {
+			t.Error("expected CaseSensitive to be true")
+		}
+		if !config.TrimWhitespace {
+			t.Error("expected TrimWhitespace to be true")
+		}
+		if !config.AllowSelfCloseTags {
+			t.Error("expected AllowSelfCloseTags to be true")
+		}
+		if len(config.VoidElements) != 0 {
+			t.Errorf("expected no void elements, got %v", config.VoidElements)
+		}
+		if !config.DecodeEntities {
+			t.Error("expected DecodeEntities to be true")
+		}
+		if !config.NormalizeLineEndings {
+			t.Error("expected NormalizeLineEndings to be true")
+		}
+		if config.CoreMatcher == nil || config.AttributeProcessor == nil {
+			t.Error("expected CoreMatcher and AttributeProcessor to be set")
+		}
+
+		defaults := DefaultConfig()
+		if defaults.DecodeEntities || defaults.NormalizeLineEndings {
+			t.Error("DefaultConfig should not decode entities or normalize line endings")
+		}
+	})
+
+	t.Run("parses conformant XML", func(t *testing.T) {
+		input := "<catalog xmlns:x=\"urn:x\">\r\n" +
+			"  <book id=\"b&amp;1\">Tom &amp; Jerry &#x263A;</book>\r\n" +
+			"  <x:note>line1\rline2</x:note>\r\n" +
+			"  <empty/>\r\n" +
+			"</catalog>"
+
+		doc, err := NewParserWithConfig(input, XMLConfig()).Parse()
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+
+		catalog := doc.Children[0].(*Element)
+		if len(catalog.Children) != 3 {
+			t.Fatalf("expected 3 children, got %d", len(catalog.Children))
+		}
+
+		book := catalog.Children[0].(*Element)
+		if book.Attributes["id"] != "b&1" {
+			t.Errorf("expected decoded attribute, got %q", book.Attributes["id"])
+		}
+		if text := book.Children[0].(*Text).Content; text != "Tom & Jerry ☺" {
+			t.Errorf("expected decoded text, got %q", text)
+		}
+
+		note := catalog.Children[1].(*Element)
+		if text := note.Children[0].(*Text).Content; text != "line1\nline2" {
+			t.Errorf("expected normalized line endings, got %q", text)
+		}
+		if note.Position().Line != 3 {
+			t.Errorf("expected note on line 3, got %v", note.Position())
+		}
+	})
+
+	t.Run("rejects void element shorthand", func(t *testing.T) {
+		_, err := NewParserWithConfig("<p>line<br>next</p>", XMLConfig()).Parse()
+		if err == nil {
+			t.Fatal("expected error for unclosed <br>")
+		}
+		if !strings.Contains(err.Error(), "mismatched tags") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("case-sensitive close tags", func(t *testing.T) {
+		if _, err := NewParserWithConfig("<Item></item>", XMLConfig()).Parse(); err == nil {
+			t.Error("expected mismatched tag error")
+		}
+	})
+}
+
+// TestDecodeEntities 测试实体解码
+func TestDecodeEntities(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"&lt;tag&gt;", "<tag>"},
+		{"&quot;&apos;&amp;", "\"'&"},
+		{"&#65;&#x42;&#X43;", "ABC"},
+		{"&amp;amp;", "&amp;"},
+		{"&unknown; & &;", "&unknown; & &;"},
+		{"&#xD800;&#0;&#abc;", "&#xD800;&#0;&#abc;"},
+		{"trailing &", "trailing &"},
+	}
+
+	for _, tt := range tests {
+		if got := decodeEntities(tt.input); got != tt.expected {
+			t.Errorf("decodeEntities(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+// TestDecodeEntitiesTextRun 测试实体与普通字符混合的文本作为整体解码为单个文本节点
+func TestDecodeEntitiesTextRun(t *testing.T) {
+	config := DefaultConfig()
+	config.DecodeEntities = true
+
+	for input, expected := range map[string]string{
+		"<p>a&amp;&lt;b</p>":      "a&<b",
+		"<p>x &amp;amp; y</p>":    "x &amp; y",
+		"<p>&#65;&amp;&#x42;</p>": "A&B",
+	} {
+		doc, err := NewParserWithConfig(input, config).Parse()
+		if err != nil {
+			t.Fatalf("parse error for %q: %v", input, err)
+		}
+		p := doc.Children[0].(*Element)
+		if len(p.Children) != 1 {
+			t.Fatalf("%q: expected a single text node, got %d children", input, len(p.Children))
+		}
+		if text, ok := p.Children[0].(*Text); !ok || text.Content != expected {
+			t.Errorf("%q: expected text %q, got %#v", input, expected, p.Children[0])
+		}
+	}
+}
+
+// TestOnUnknownEntity 测试自定义实体解析回调
+func TestOnUnknownEntity(t *testing.T) {
+	input := `<p title="&widget;">a &widget; &other; &amp;</p>`
+
+	config := DefaultConfig()
+	config.DecodeEntities = true
+	var seen []string
+	config.OnUnknownEntity = func(name string) (string, bool) {
+		seen = append(seen, name)
+		if name == "widget" {
+			return "<widget/>", true
+		}
+		return "", false
+	}
+
+	doc, err := NewParserWithConfig(input, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	p := doc.Children[0].(*Element)
+	if got := p.Attributes["title"]; got != "<widget/>" {
+		t.Errorf("expected attribute entity resolved, got %q", got)
+	}
+	if got := p.Children[0].(*Text).Content; got != "a <widget/> &other; &" {
+		t.Errorf("expected text entity resolved, got %q", got)
+	}
+	if strings.Join(seen, ",") != "widget,widget,other" {
+		t.Errorf("expected callback only for unknown named entities, got %v", seen)
+	}
+
+	t.Run("literal without resolver", func(t *testing.T) {
+		config := DefaultConfig()
+		config.DecodeEntities = true
+		doc, _ := NewParserWithConfig(input, config).Parse()
+		if got := doc.Children[0].(*Element).Children[0].(*Text).Content; got != "a &widget; &other; &" {
+			t.Errorf("expected unknown entities kept literally, got %q", got)
+		}
+	})
+}
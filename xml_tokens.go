@@ -0,0 +1,48 @@
+package markit
+
+import "encoding/xml"
+
+// XMLTokens 将文档转换为 encoding/xml 的 token 序列，可直接交给 xml.Encoder 或其他使用标准库 token 的代码
+// 命名空间仅做最小处理：限定名（如 svg:rect）原样保存在 Name.Local 中，xmlns 声明作为普通属性输出
+func (d *Document) XMLTokens() []xml.Token {
+	var tokens []xml.Token
+	for _, child := range d.Children {
+		tokens = appendXMLTokens(tokens, child)
+	}
+	return tokens
+}
+
+// appendXMLTokens 将节点及其子树转换为 token 并追加到 tokens
+func appendXMLTokens(tokens []xml.Token, node Node) []xml.Token {
+	switch n := node.(type) {
+	case *Element:
+		start := xml.StartElement{Name: xml.Name{Local: n.TagName}}
+		for _, key := range attributeKeys(n, false) {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: key}, Value: n.Attributes[key]})
+		}
+		tokens = append(tokens, start)
+		for _, child := range n.Children {
+			tokens = appendXMLTokens(tokens, child)
+		}
+		tokens = append(tokens, start.End())
+	case *Text:
+		tokens = append(tokens, xml.CharData(n.Content))
+	case *CDATA:
+		tokens = append(tokens, xml.CharData(n.Content))
+	case *Comment:
+		tokens = append(tokens, xml.Comment(n.Content))
+	case *ProcessingInstruction:
+		tokens = append(tokens, xml.ProcInst{Target: n.Target, Inst: []byte(n.Content)})
+	case *Doctype:
+		tokens = append(tokens, xml.Directive("DOCTYPE "+n.declaration()))
+	case *Fragment:
+		for _, child := range n.Children {
+			tokens = appendXMLTokens(tokens, child)
+		}
+	case *Document:
+		for _, child := range n.Children {
+			tokens = appendXMLTokens(tokens, child)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,65 @@
+package markit
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// TestXMLTokens 测试转换为 encoding/xml token
+func TestXMLTokens(t *testing.T) {
+	input := `<root id="1" lang="en"><item>a &amp; b</item><!--note--><empty></empty><svg:rect xmlns:svg="urn:svg" w="2"></svg:rect></root>`
+	config := DefaultConfig()
+	config.DecodeEntities = true
+	doc, err := NewParserWithConfig(input, config).Parse()
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var sb strings.Builder
+	encoder := xml.NewEncoder(&sb)
+	for _, token := range doc.XMLTokens() {
+		if err := encoder.EncodeToken(token); err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+
+	rendered, err := NewRendererWithOptions(&RenderOptions{
+		CompactMode:       true,
+		EscapeText:        true,
+		EmptyElementStyle: PairedTagStyle,
+	}).RenderToString(doc)
+	if err != nil {
+		t.Fatalf("render error: %v", err)
+	}
+	if sb.String() != rendered {
+		t.Errorf("encoder output differs from renderer:\n got: %s\nwant: %s", sb.String(), rendered)
+	}
+
+	t.Run("token kinds", func(t *testing.T) {
+		doc := &Document{Children: []Node{
+			&ProcessingInstruction{Target: "xml", Content: `version="1.0"`},
+			&Doctype{Content: "note"},
+			NewElement("a").WithChildren(&CDATA{Content: "<x>"}),
+		}}
+		tokens := doc.XMLTokens()
+		if len(tokens) != 5 {
+			t.Fatalf("expected 5 tokens, got %d", len(tokens))
+		}
+		if pi, ok := tokens[0].(xml.ProcInst); !ok || pi.Target != "xml" || string(pi.Inst) != `version="1.0"` {
+			t.Errorf("unexpected processing instruction %#v", tokens[0])
+		}
+		if dir, ok := tokens[1].(xml.Directive); !ok || string(dir) != "DOCTYPE note" {
+			t.Errorf("unexpected directive %#v", tokens[1])
+		}
+		if data, ok := tokens[3].(xml.CharData); !ok || string(data) != "<x>" {
+			t.Errorf("unexpected char data %#v", tokens[3])
+		}
+		if end, ok := tokens[4].(xml.EndElement); !ok || end.Name.Local != "a" {
+			t.Errorf("unexpected end element %#v", tokens[4])
+		}
+	})
+}